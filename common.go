@@ -3,19 +3,33 @@ Package extnet provides a portable interface for network I/O, including SCTP.
 */
 package extnet
 
+import (
+	"time"
+	"unsafe"
+)
+
 const (
 	// RxBufferSize is network recieve queue size
 	RxBufferSize = 10240
 	// BacklogSize is accept queue size
 	BacklogSize = 128
+	// DefaultCloseTimeout is default timeout of Close to wait
+	// completion of the shutdown
+	DefaultCloseTimeout = time.Second * 30
 
 	// CloseNotifyPpid is used close listener
+	//
+	// Deprecated: listener is closed without notification message.
 	CloseNotifyPpid uint32 = 4294967295
 	// CloseNotifyCotext is used close listener
+	//
+	// Deprecated: listener is closed without notification message.
 	CloseNotifyCotext uint32 = 4294967295
 )
 
-// Notificator is called when error or trace event are occured
+// Notificator is called when error or trace event are occured.
+// It is shared by all listeners and called synchronously in the receiving,
+// then Subscribe of SCTPListener or SCTPConn is preferred.
 var Notificator func(e error)
 
 type sndrcvInfo struct {
@@ -30,6 +44,67 @@ type sndrcvInfo struct {
 	assocID    assocT
 }
 
+type assocValue struct {
+	assocID assocT
+	value   uint32
+}
+
+func setAssocValue(fd, opt int, id assocT, v uint32) error {
+	attr := assocValue{
+		assocID: id,
+		value:   v}
+	l := unsafe.Sizeof(attr)
+	p := unsafe.Pointer(&attr)
+
+	return setSockOpt(fd, opt, p, l)
+}
+
+func getAssocValue(fd, opt int, id assocT) (uint32, error) {
+	attr := assocValue{assocID: id}
+	l := unsafe.Sizeof(attr)
+	p := unsafe.Pointer(&attr)
+
+	e := getSockOpt(fd, opt, p, &l)
+	return attr.value, e
+}
+
+// putUint32 writes v to b in host byte order without alignment.
+func putUint32(b []byte, v uint32) {
+	copy(b, (*[4]byte)(unsafe.Pointer(&v))[:])
+}
+
+func getUint32(b []byte) (v uint32) {
+	copy((*[4]byte)(unsafe.Pointer(&v))[:], b)
+	return
+}
+
+func putUint16(b []byte, v uint16) {
+	copy(b, (*[2]byte)(unsafe.Pointer(&v))[:])
+}
+
+func getUint16(b []byte) (v uint16) {
+	copy((*[2]byte)(unsafe.Pointer(&v))[:], b)
+	return
+}
+
+// setEvent enables or disables the notification type t.
+func setEvent(fd int, t uint16, on bool) error {
+	type opt struct {
+		assocID assocT
+		seType  uint16
+		seOn    uint8
+	}
+
+	event := opt{seType: t}
+	if on {
+		event.seOn = 1
+	}
+	l := unsafe.Sizeof(event)
+	p := unsafe.Pointer(&event)
+
+	return setSockOpt(fd, sctpEvent, p, l)
+}
+
 type timeoutError struct{}
 
 func (e *timeoutError) Error() string   { return "i/o timeout" }
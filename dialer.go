@@ -1,8 +1,11 @@
 package extnet
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
+	"syscall"
 	"time"
 	"unsafe"
 )
@@ -18,6 +21,48 @@ type SCTPDialer struct {
 
 	PPID      uint32
 	Unordered bool
+
+	// OneToOne uses one-to-one style (SOCK_STREAM) socket
+	// instead of one-to-many style (SOCK_SEQPACKET) socket.
+	// Each connection has own socket in this style, and
+	// the connections are not closed when the listener is closed.
+	OneToOne bool
+
+	// StreamReset enables stream reconfiguration (RFC 6525),
+	// then streams of the association can be reset or added.
+	StreamReset bool
+
+	// PartialReliability enables partially reliable SCTP (RFC 3758),
+	// then messages can be abandoned by the policy of PRInfo.
+	PartialReliability bool
+
+	// Scheduler is the stream scheduler of the association,
+	// the default scheduler of the kernel is used if SchedulerDefault.
+	Scheduler StreamScheduler
+
+	// Interleave enables user message interleaving (RFC 8260),
+	// then a large message does not block messages of other streams.
+	// Linux returns EPERM unless net.sctp.intl_enable is 1.
+	Interleave bool
+
+	// AuthChunks are chunk types that must be authenticated with
+	// SCTP-AUTH (RFC 4895), and HMACIdents are HMAC identifiers
+	// in order of preference. SCTP-AUTH is enabled if either is set.
+	AuthChunks []uint8
+	HMACIdents []uint16
+
+	// PeerAddrParams is the default parameters of peer addresses,
+	// Addr of PeerAddrParams must be nil.
+	PeerAddrParams *PeerAddrParams
+
+	// CloseTimeout is the timeout of Close of the connections,
+	// see SetCloseTimeout of SCTPConn.
+	CloseTimeout time.Duration
+
+	// Notifications are SCTP notifications that the socket subscribes,
+	// the platform default is used if 0.
+	// NotifyAssocChange is always subscribed.
+	Notifications Notification
 }
 
 // DialSCTP connects from the local address laddr
@@ -31,11 +76,18 @@ func DialSCTP(laddr, raddr *SCTPAddr) (c *SCTPConn, e error) {
 			Addr:   raddr,
 			Err:    fmt.Errorf("no remote address")}
 	}
-	return dial(&SCTPDialer{LocalAddr: laddr}, raddr)
+	return dial(context.Background(), &SCTPDialer{LocalAddr: laddr}, raddr)
 }
 
 // Dial connects to the addr.
 func (d *SCTPDialer) Dial(n, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), n, addr)
+}
+
+// DialContext connects to the addr until the context is done.
+// If the context is done before the connection is established,
+// the association is aborted and the socket is closed.
+func (d *SCTPDialer) DialContext(ctx context.Context, n, addr string) (net.Conn, error) {
 	switch n {
 	case "sctp", "sctp4", "sctp6":
 	default:
@@ -51,18 +103,22 @@ func (d *SCTPDialer) Dial(n, addr string) (net.Conn, error) {
 	if e != nil {
 		return nil, e
 	}
-	return dial(d, ra)
+	return dial(ctx, d, ra)
 }
 
-func dial(d *SCTPDialer, addr *SCTPAddr) (*SCTPConn, error) {
-	l, e := listen(d)
+func dial(ctx context.Context, d *SCTPDialer, addr *SCTPAddr) (*SCTPConn, error) {
+	l, e := d.open(!d.OneToOne)
 	if e != nil {
 		return nil, e
 	}
 
-	// connect SCTP connection to raddr
+	// connect SCTP connection to raddr without waiting,
+	// result of the connection is notified by association change
 	ptr, n := addr.rawAddr()
-	i, e := sctpConnectx(l.sock, ptr, n)
+	i, e := sctpConnectx(l.sock(), ptr, n)
+	if e == syscall.EINPROGRESS {
+		e = nil
+	}
 	if e != nil {
 		e = &net.OpError{
 			Op:     "connect",
@@ -70,20 +126,39 @@ func dial(d *SCTPDialer, addr *SCTPAddr) (*SCTPConn, error) {
 			Source: l.Addr(),
 			Addr:   addr,
 			Err:    e}
-		l.Close()
+		l.file().close()
 		return nil, e
 	}
+	l.failed = make(chan assocT, 1)
+	l.start()
+
 	for {
-		c, e := l.AcceptSCTP()
+		select {
+		case c := <-l.accept:
+			if c.id != i {
+				c.Abort("close")
+			} else if e = ctx.Err(); e == nil {
+				l.setClosed()
+				return c, nil
+			} else {
+				c.Abort("canceled")
+			}
+		case f := <-l.failed:
+			if f == i {
+				e = errors.New("failed to setup association")
+			}
+		case <-ctx.Done():
+			e = ctx.Err()
+		}
 		if e != nil {
 			l.Close()
-			return nil, e
-		}
-		if c.id == i {
-			l.close = make(chan bool)
-			return c, nil
+			return nil, &net.OpError{
+				Op:     "dial",
+				Net:    "sctp",
+				Source: d.LocalAddr,
+				Addr:   addr,
+				Err:    e}
 		}
-		c.Abort("close")
 	}
 }
 
@@ -109,6 +184,16 @@ func (d *SCTPDialer) Listen() (net.Listener, error) {
 }
 
 func listen(d *SCTPDialer) (*SCTPListener, error) {
+	l, e := d.open(true)
+	if e == nil {
+		l.start()
+	}
+	return l, e
+}
+
+// open creates new socket, the socket start listening if listening is true.
+// The socket is not handled until start is called.
+func (d *SCTPDialer) open(listening bool) (*SCTPListener, error) {
 	if d.LocalAddr == nil {
 		return nil, &net.OpError{
 			Op:   "listen",
@@ -124,7 +209,13 @@ func listen(d *SCTPDialer) (*SCTPListener, error) {
 	}
 
 	// start listen
-	e = sockListen(sock)
+	if listening {
+		e = sockListen(sock)
+	}
+	var f *sockFile
+	if e == nil {
+		f, e = newSockFile(sock)
+	}
 	if e != nil {
 		sockClose(sock)
 		return nil, &net.OpError{
@@ -137,20 +228,29 @@ func listen(d *SCTPDialer) (*SCTPListener, error) {
 
 	// create listener
 	l := &SCTPListener{
-		sock:   sock,
-		con:    make(map[assocT]*SCTPConn),
-		accept: make(chan *SCTPConn, BacklogSize),
-		ppid:   d.PPID}
+		sctpSocket: sctpSocket{f: f},
+		oneToOne:   d.OneToOne,
+		listening:  listening,
+		con:        make(map[assocT]*SCTPConn),
+		accept:     make(chan *SCTPConn, BacklogSize),
+		done:       make(chan bool),
+		ppid:       d.PPID,
+		timeout:    d.CloseTimeout}
 	if d.Unordered {
 		l.uo = sctpUnordered
 	}
+	return l, nil
+}
 
-	// start reading buffer
+// start reading buffer, or accepting socket of one-to-one style.
+func (l *SCTPListener) start() {
 	r := make(chan bool)
-	go read(l, r)
+	if l.oneToOne && l.listening {
+		go accept(l, r)
+	} else {
+		go read(l, l.file(), r)
+	}
 	<-r
-
-	return l, nil
 }
 
 // bind SCTP socket
@@ -159,14 +259,18 @@ func (d *SCTPDialer) bindsocket(laddr *SCTPAddr) (int, error) {
 	// create SCTP connection socket
 	sock := 0
 	var e error
-	if laddr.IP[0].To4() != nil {
-		sock, e = sockOpenV4()
-	} else if laddr.IP[0].To16() != nil {
-		sock, e = sockOpenV6()
-	} else {
+	st := syscall.SOCK_SEQPACKET
+	if d.OneToOne {
+		st = syscall.SOCK_STREAM
+	}
+	if ptr, _ := laddr.rawAddr(); ptr == nil {
 		e = &net.AddrError{
 			Err:  "unknown address format",
 			Addr: laddr.String()}
+	} else if laddr.family() == syscall.AF_INET {
+		sock, e = sockOpenV4(st)
+	} else {
+		sock, e = sockOpenV6(st)
 	}
 	if e != nil {
 		e = &net.OpError{
@@ -178,7 +282,7 @@ func (d *SCTPDialer) bindsocket(laddr *SCTPAddr) (int, error) {
 	}
 
 	// set notifycation enabled
-	e = setNotify(sock)
+	e = setNotify(sock, d.Notifications)
 	if e != nil {
 		sockClose(sock)
 		e = &net.OpError{
@@ -190,17 +294,11 @@ func (d *SCTPDialer) bindsocket(laddr *SCTPAddr) (int, error) {
 	}
 
 	// set init parameter
-	type opt struct {
-		o uint16
-		i uint16
-		a uint16
-		t uint16
-	}
-	attr := opt{
-		o: uint16(d.OutStream),
-		i: uint16(d.InStream),
-		a: uint16(d.MaxAttempts),
-		t: uint16(d.InitTimeout)}
+	attr := (&InitMsg{
+		OutStream:   int(d.OutStream),
+		InStream:    int(d.InStream),
+		MaxAttempts: int(d.MaxAttempts),
+		InitTimeout: d.InitTimeout}).raw()
 	l := unsafe.Sizeof(attr)
 	p := unsafe.Pointer(&attr)
 
@@ -215,9 +313,93 @@ func (d *SCTPDialer) bindsocket(laddr *SCTPAddr) (int, error) {
 		return -1, e
 	}
 
+	// set stream reconfiguration enabled
+	if d.StreamReset {
+		e = enableStreamReset(sock)
+	}
+	if e != nil {
+		sockClose(sock)
+		e = &net.OpError{
+			Op:   "setsockopt",
+			Net:  "sctp",
+			Addr: laddr,
+			Err:  e}
+		return -1, e
+	}
+
+	// set partial reliability enabled
+	if d.PartialReliability {
+		e = setAssocValue(sock, sctpPrSupported, 0, 1)
+	}
+	if e != nil {
+		sockClose(sock)
+		e = &net.OpError{
+			Op:   "setsockopt",
+			Net:  "sctp",
+			Addr: laddr,
+			Err:  e}
+		return -1, e
+	}
+
+	// set stream scheduler
+	if d.Scheduler != SchedulerDefault {
+		e = setAssocValue(sock, sctpStreamScheduler, 0, d.Scheduler.value())
+	}
+	if e != nil {
+		sockClose(sock)
+		e = &net.OpError{
+			Op:   "setsockopt",
+			Net:  "sctp",
+			Addr: laddr,
+			Err:  e}
+		return -1, e
+	}
+
+	// set user message interleaving enabled
+	if d.Interleave {
+		e = enableInterleaving(sock)
+	}
+	if e != nil {
+		sockClose(sock)
+		e = &net.OpError{
+			Op:   "setsockopt",
+			Net:  "sctp",
+			Addr: laddr,
+			Err:  e}
+		return -1, e
+	}
+
+	// set SCTP-AUTH enabled
+	if len(d.AuthChunks) != 0 || len(d.HMACIdents) != 0 {
+		e = enableAuth(sock, d.AuthChunks, d.HMACIdents)
+	}
+	if e != nil {
+		sockClose(sock)
+		e = &net.OpError{
+			Op:   "setsockopt",
+			Net:  "sctp",
+			Addr: laddr,
+			Err:  e}
+		return -1, e
+	}
+
+	// set default peer address parameters
+	if d.PeerAddrParams != nil {
+		e = setPeerAddrParams(sock, d.PeerAddrParams.raw(0, nil))
+	}
+	if e != nil {
+		sockClose(sock)
+		e = &net.OpError{
+			Op:   "setsockopt",
+			Net:  "sctp",
+			Addr: laddr,
+			Err:  e}
+		return -1, e
+	}
+
 	// bind SCTP connection
 	ptr, n := laddr.rawAddr()
-	e = sctpBindx(sock, ptr, n)
+	e = sctpBindx(sock, ptr, n, sctpBindxAddAddr)
 	if e != nil {
 		e = &net.OpError{
 			Op:   "bindx",
@@ -229,3 +411,11 @@ func (d *SCTPDialer) bindsocket(laddr *SCTPAddr) (int, error) {
 	}
 	return sock, nil
 }
+
+func enableStreamReset(sock int) error {
+	if e := setAssocValue(sock, sctpReconfigSupported, 0, 1); e != nil {
+		return e
+	}
+	return setAssocValue(sock, sctpEnableStreamReset, 0,
+		sctpEnableResetStream|sctpEnableResetAssoc|sctpEnableChangeAssoc)
+}
@@ -1,6 +1,10 @@
 package extnet
 
-import "testing"
+import (
+	"context"
+	"testing"
+	"time"
+)
 
 func TestListenSCTP(t *testing.T) {
 	Notificator = func(e error) { t.Log(e) }
@@ -131,3 +135,127 @@ func TestDial(t *testing.T) {
 		t.Errorf("close faied: %s", e)
 	}
 }
+
+func TestDialOneToOne(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	d0 := &SCTPDialer{LocalAddr: a0, OneToOne: true}
+	l0a, e := d0.Listen()
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+	l0 := l0a.(*SCTPListener)
+
+	d1 := &SCTPDialer{LocalAddr: a1, OneToOne: true}
+	c1a, e := d1.Dial("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+	c1 := c1a.(*SCTPConn)
+
+	c0, e := l0.AcceptSCTP()
+	if e != nil {
+		t.Fatalf("accept faied: %s", e)
+	}
+	if c0.sock() == l0.sock() {
+		t.Errorf("accepted connection must have own socket")
+	}
+	s := c0.RemoteAddr().String()
+	if s != testAddrs[1] {
+		t.Errorf("output %s is not same as %s", s, testAddrs[1])
+	}
+
+	buf := make([]byte, 1024)
+	if n, e := c0.Write([]byte(testStr)); e != nil {
+		t.Errorf("write data failed: %s", e)
+	} else if n != len(testStr) {
+		t.Errorf("write data length is invalid: %d is not equal %d", n, len(testStr))
+	} else if n, e = c1.Read(buf); e != nil {
+		t.Errorf("read data failed: %s", e)
+	} else if n != len(testStr) {
+		t.Errorf("read data length is invalid: %d is not equal %d", n, len(testStr))
+	}
+
+	e = c1.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+
+	e = l0.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
+
+func TestContext(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	l0, e := ListenSCTP("sctp", a0)
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	if _, e = l0.AcceptContext(ctx); e == nil {
+		t.Errorf("accept must fail after the context is done")
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	d := &SCTPDialer{LocalAddr: a1}
+	if _, e = d.DialContext(ctx, "sctp", testAddrs[0]); e == nil {
+		t.Errorf("dial must fail with canceled context")
+	}
+
+	c1, e := d.DialContext(context.Background(), "sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+	c0, e := l0.AcceptContext(context.Background())
+	if e != nil {
+		t.Fatalf("accept faied: %s", e)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	if _, _, e = c0.ReadMsgContext(ctx, make([]byte, 1024)); e == nil {
+		t.Errorf("read must fail after the context is done")
+	}
+
+	if _, e = c1.Write([]byte("hello")); e != nil {
+		t.Errorf("write faied: %s", e)
+	}
+	b := make([]byte, 1024)
+	n, _, e := c0.ReadMsgContext(context.Background(), b)
+	if e != nil {
+		t.Errorf("read faied: %s", e)
+	} else if string(b[:n]) != "hello" {
+		t.Errorf("output %s is not same as hello", b[:n])
+	}
+
+	if e = c1.Close(); e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+	if e = l0.Close(); e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
@@ -11,10 +11,20 @@ import (
 
 // SCTPAddr represents the address of a SCTP end point.
 type SCTPAddr struct {
-	IP   []net.IP
+	IP []net.IP
+	// Zone is the IPv6 scoped addressing zone of each IP.
+	// It may be shorter than IP when trailing IPs have no zone.
+	Zone []string
 	Port int
 }
 
+func (a *SCTPAddr) zone(i int) string {
+	if i < len(a.Zone) {
+		return a.Zone[i]
+	}
+	return ""
+}
+
 // ResolveSCTPAddr  parses addr as a SCTP address
 // of the form "host:port" or "[ipv6-host%zone]:port" and
 // resolves a pair of domain name and port name on the network net,
@@ -68,89 +78,74 @@ func ResolveSCTPAddr(network, str string) (*SCTPAddr, error) {
 				Addr: str}
 		}
 		addr.IP[i] = ip.IP
-	}
-
-	if len(addr.IP) > 1 {
-		for i := 0; i < len(addr.IP)-1; i++ {
-			if (addr.IP[i].To4() != nil && addr.IP[i+1].To4() == nil) ||
-				(addr.IP[i].To4() == nil && addr.IP[i+1].To4() != nil) {
-				return nil, &net.AddrError{
-					Err:  "mismatch of address version",
-					Addr: str}
+		if ip.Zone != "" {
+			if addr.Zone == nil {
+				addr.Zone = make([]string, len(a))
 			}
+			addr.Zone[i] = ip.Zone
 		}
 	}
 	return addr, nil
 }
 
+// family returns AF_INET if all addresses are IPv4, or AF_INET6.
+func (a *SCTPAddr) family() int {
+	for _, i := range a.IP {
+		if i.To4() == nil {
+			return syscall.AF_INET6
+		}
+	}
+	return syscall.AF_INET
+}
+
+// rawAddr returns packed array of sockaddr_in and sockaddr_in6.
 func (a *SCTPAddr) rawAddr() (unsafe.Pointer, int) {
 	if len(a.IP) == 0 {
 		return nil, 0
 	}
-	p := uint16(a.Port<<8) & 0xff00
-	p |= uint16(a.Port>>8) & 0x00ff
 
-	if a.IP[0].To4() != nil {
-		addr := make([]syscall.RawSockaddrInet4, len(a.IP))
-		for n, i := range a.IP {
-			i = i.To4()
-			if i == nil {
-				return nil, 0
-			}
-			addr[n].Family = syscall.AF_INET
-			addr[n].Port = p
-			addr[n].Addr = [4]byte{i[0], i[1], i[2], i[3]}
-		}
-		return unsafe.Pointer(&addr[0]), len(a.IP)
-	} else if a.IP[0].To16() != nil {
-		addr := make([]syscall.RawSockaddrInet6, len(a.IP))
-		for n, i := range a.IP {
-			if i.To4() != nil {
-				return nil, 0
-			}
-			i = i.To16()
-			addr[n].Family = syscall.AF_INET6
-			addr[n].Port = p
-			// addr[n].Flowinfo
-			addr[n].Addr = [net.IPv6len]byte{}
-			for j := 0; j < net.IPv6len; j++ {
-				addr[n].Addr[j] = i[j]
-			}
-			// addr[n].Scope_id
+	buf := make([]byte, 0, len(a.IP)*int(sockaddrLen(net.IPv6zero)))
+	for n, i := range a.IP {
+		l := sockaddrLen(i)
+		if l == 0 {
+			return nil, 0
 		}
-		return unsafe.Pointer(&addr[0]), len(a.IP)
-	} else {
-		return nil, 0
+		s := rawSockaddr(i, a.zone(n), a.Port)
+		buf = append(buf, s[:l]...)
 	}
+	return unsafe.Pointer(&buf[0]), len(a.IP)
 }
 
 func resolveFromRawAddr(ptr unsafe.Pointer, n int) *SCTPAddr {
 	addr := &SCTPAddr{}
 	p := 0
-	addr.IP = make([]net.IP, n)
+	addr.IP = make([]net.IP, 0, n)
 
-	switch (*(*syscall.RawSockaddrAny)(ptr)).Addr.Family {
-	case syscall.AF_INET:
-		p = int((*(*syscall.RawSockaddrInet4)(ptr)).Port)
+	o := uintptr(0)
+	for i := 0; i < n; i++ {
+		sa := unsafe.Pointer(uintptr(ptr) + o)
+		ip := ipFromSockaddr(sa)
+		if ip == nil {
+			// following addresses can not be decoded with unknown family
+			break
+		}
+		addr.IP = append(addr.IP, ip)
 
-		for i := 0; i < n; i++ {
-			a := *(*syscall.RawSockaddrInet4)(unsafe.Pointer(
-				uintptr(ptr) + uintptr(16*i)))
-			addr.IP[i] = net.IPv4(a.Addr[0], a.Addr[1], a.Addr[2], a.Addr[3])
+		if i == 0 {
+			p = int((*syscall.RawSockaddrInet4)(sa).Port)
 		}
-	case syscall.AF_INET6:
-		p = int((*(*syscall.RawSockaddrInet6)(ptr)).Port)
-
-		for i := 0; i < n; i++ {
-			a := *(*syscall.RawSockaddrInet6)(unsafe.Pointer(
-				uintptr(ptr) + uintptr(28*i)))
-			addr.IP[i] = make([]byte, net.IPv6len)
-			for j := 0; j < net.IPv6len; j++ {
-				addr.IP[i][j] = a.Addr[j]
+		if (*syscall.RawSockaddr)(sa).Family == syscall.AF_INET {
+			o += unsafe.Sizeof(syscall.RawSockaddrInet4{})
+			continue
+		}
+		a := (*syscall.RawSockaddrInet6)(sa)
+		o += unsafe.Sizeof(*a)
+		if a.Scope_id != 0 && ip.To4() == nil {
+			if addr.Zone == nil {
+				addr.Zone = make([]string, n)
 			}
+			addr.Zone[i] = indexToZone(a.Scope_id)
 		}
-	default:
-		panic("invalid family of address")
 	}
 
 	addr.Port = (p & 0xff) << 8
@@ -158,6 +153,88 @@ func resolveFromRawAddr(ptr unsafe.Pointer, n int) *SCTPAddr {
 	return addr
 }
 
+func (a *SCTPAddr) index(ip net.IP) int {
+	for n, i := range a.IP {
+		if i.Equal(ip) {
+			return n
+		}
+	}
+	return -1
+}
+
+func zoneToIndex(zone string) uint32 {
+	if zone == "" {
+		return 0
+	}
+	if ifi, e := net.InterfaceByName(zone); e == nil {
+		return uint32(ifi.Index)
+	}
+	if n, e := strconv.ParseUint(zone, 10, 32); e == nil {
+		return uint32(n)
+	}
+	return 0
+}
+
+func indexToZone(index uint32) string {
+	if index == 0 {
+		return ""
+	}
+	if ifi, e := net.InterfaceByIndex(int(index)); e == nil {
+		return ifi.Name
+	}
+	return strconv.FormatUint(uint64(index), 10)
+}
+
+// sockaddrStorage is same as struct sockaddr_storage.
+type sockaddrStorage [128]byte
+
+// sockaddrLen returns the size of sockaddr_in or sockaddr_in6 for ip.
+func sockaddrLen(ip net.IP) uintptr {
+	if ip.To4() != nil {
+		return unsafe.Sizeof(syscall.RawSockaddrInet4{})
+	} else if ip.To16() != nil {
+		return unsafe.Sizeof(syscall.RawSockaddrInet6{})
+	}
+	return 0
+}
+
+func rawSockaddr(ip net.IP, zone string, port int) (s sockaddrStorage) {
+	p := uint16(port<<8) & 0xff00
+	p |= uint16(port>>8) & 0x00ff
+
+	if i := ip.To4(); i != nil {
+		addr := (*syscall.RawSockaddrInet4)(unsafe.Pointer(&s[0]))
+		addr.Family = syscall.AF_INET
+		addr.Port = p
+		addr.Addr = [4]byte{i[0], i[1], i[2], i[3]}
+	} else if i := ip.To16(); i != nil {
+		addr := (*syscall.RawSockaddrInet6)(unsafe.Pointer(&s[0]))
+		addr.Family = syscall.AF_INET6
+		addr.Port = p
+		for j := 0; j < net.IPv6len; j++ {
+			addr.Addr[j] = i[j]
+		}
+		addr.Scope_id = zoneToIndex(zone)
+	}
+	return
+}
+
+func ipFromSockaddr(p unsafe.Pointer) net.IP {
+	switch (*syscall.RawSockaddr)(p).Family {
+	case syscall.AF_INET:
+		a := (*syscall.RawSockaddrInet4)(p)
+		return net.IPv4(a.Addr[0], a.Addr[1], a.Addr[2], a.Addr[3])
+	case syscall.AF_INET6:
+		a := (*syscall.RawSockaddrInet6)(p)
+		ip := make([]byte, net.IPv6len)
+		for j := 0; j < net.IPv6len; j++ {
+			ip[j] = a.Addr[j]
+		}
+		return ip
+	}
+	return nil
+}
+
 func (a *SCTPAddr) String() string {
 	var b bytes.Buffer
 
@@ -169,6 +246,10 @@ func (a *SCTPAddr) String() string {
 			b.WriteRune('/')
 			b.WriteRune('[')
 			b.WriteString(i.String())
+			if z := a.zone(n); z != "" {
+				b.WriteRune('%')
+				b.WriteString(z)
+			}
 			b.WriteRune(']')
 		}
 	}
@@ -1,6 +1,9 @@
 package extnet
 
-import "testing"
+import (
+	"net"
+	"testing"
+)
 
 func TestResolveSCTPAddr(t *testing.T) {
 	str := testAddrs[0]
@@ -53,14 +56,48 @@ func TestResolveSCTPAddrNetworkMissmatch(t *testing.T) {
 	}
 }
 
-func TestResolveSCTPAddrAddressMissmatch(t *testing.T) {
+func TestResolveSCTPAddrMixedVersion(t *testing.T) {
 	str := testAddrs[0]
 	if str[0] == '[' {
 		str = "127.0.0.1/" + str
 	} else {
 		str = "[::1]/" + str
 	}
-	if _, e := ResolveSCTPAddr("sctp", str); e == nil {
+	a, e := ResolveSCTPAddr("sctp", str)
+	if e != nil {
+		t.Fatalf("failure in mixed version address: %s", e)
+	} else if a.String() != str {
+		t.Errorf("output %s is not same as %s", a.String(), str)
+	}
+
+	ptr, n := a.rawAddr()
+	if r := resolveFromRawAddr(ptr, n); r.String() != str {
+		t.Errorf("output %s is not same as %s", r.String(), str)
+	}
+
+	if _, e := ResolveSCTPAddr("sctp4", str); e == nil {
+		t.Error("no failure in version mismatch case")
+	}
+	if _, e := ResolveSCTPAddr("sctp6", str); e == nil {
 		t.Error("no failure in version mismatch case")
 	}
 }
+
+func TestResolveSCTPAddrZone(t *testing.T) {
+	ifs, e := net.Interfaces()
+	if e != nil || len(ifs) == 0 {
+		t.Skip("no network interface")
+	}
+	str := "[fe80::1%" + ifs[0].Name + "]/[fe80::2]:10000"
+	a, e := ResolveSCTPAddr("sctp", str)
+	if e != nil {
+		t.Fatalf("failure in scoped ipv6 address: %s", e)
+	} else if a.String() != str {
+		t.Errorf("output %s is not same as %s", a.String(), str)
+	}
+
+	ptr, n := a.rawAddr()
+	if r := resolveFromRawAddr(ptr, n); r.String() != str {
+		t.Errorf("output %s is not same as %s", r.String(), str)
+	}
+}
@@ -0,0 +1,225 @@
+package extnet
+
+import (
+	"net"
+	"unsafe"
+)
+
+// HMAC identifiers for SCTP-AUTH
+const (
+	HMACSHA1   uint16 = 1
+	HMACSHA256 uint16 = 3
+)
+
+// Chunk types that can be authenticated with SCTP-AUTH
+const (
+	ChunkData       uint8 = 0x00
+	ChunkSack       uint8 = 0x03
+	ChunkHeartbeat  uint8 = 0x04
+	ChunkAbort      uint8 = 0x06
+	ChunkIData      uint8 = 0x40
+	ChunkASCONFAck  uint8 = 0x80
+	ChunkReconf     uint8 = 0x82
+	ChunkForwardTSN uint8 = 0xc0
+	ChunkASCONF     uint8 = 0xc1
+)
+
+// enableAuth set SCTP-AUTH enabled with chunks and hmacs.
+func enableAuth(sock int, chunks []uint8, hmacs []uint16) error {
+	if e := setAssocValue(sock, sctpAuthSupported, 0, 1); e != nil {
+		return e
+	}
+
+	for _, c := range chunks {
+		if e := setSockOpt(sock, sctpAuthChunk,
+			unsafe.Pointer(&c), unsafe.Sizeof(c)); e != nil {
+			return e
+		}
+	}
+
+	if len(hmacs) == 0 {
+		return nil
+	}
+	type opt struct {
+		number uint32
+	}
+	l := unsafe.Sizeof(opt{})
+	buf := make([]byte, l+uintptr(len(hmacs))*2)
+
+	attr := (*opt)(unsafe.Pointer(&buf[0]))
+	attr.number = uint32(len(hmacs))
+	for i, id := range hmacs {
+		*(*uint16)(unsafe.Pointer(&buf[l+uintptr(i)*2])) = id
+	}
+	return setSockOpt(sock, sctpHmacIdent, unsafe.Pointer(&buf[0]), uintptr(len(buf)))
+}
+
+func setAuthKey(sock int, id assocT, n uint16, key []byte) error {
+	type opt struct {
+		assocID assocT
+		number  uint16
+		length  uint16
+	}
+	l := unsafe.Sizeof(opt{})
+	buf := make([]byte, l+uintptr(len(key)))
+
+	attr := (*opt)(unsafe.Pointer(&buf[0]))
+	attr.assocID = id
+	attr.number = n
+	attr.length = uint16(len(key))
+	copy(buf[l:], key)
+
+	return setSockOpt(sock, sctpAuthKey, unsafe.Pointer(&buf[0]), uintptr(len(buf)))
+}
+
+func setAuthKeyID(sock, opt int, id assocT, n uint16) error {
+	type keyid struct {
+		assocID assocT
+		number  uint16
+	}
+	attr := keyid{
+		assocID: id,
+		number:  n}
+	l := unsafe.Sizeof(attr)
+	p := unsafe.Pointer(&attr)
+
+	return setSockOpt(sock, opt, p, l)
+}
+
+func getAuthChunks(sock, opt int, id assocT) ([]uint8, error) {
+	type chunks struct {
+		assocID assocT
+		number  uint32
+	}
+	// chunk types are 1 byte, then 256 bytes are enough for all types
+	l := unsafe.Sizeof(chunks{})
+	buf := make([]byte, l+256)
+
+	attr := (*chunks)(unsafe.Pointer(&buf[0]))
+	attr.assocID = id
+	n := uintptr(len(buf))
+
+	if e := getSockOpt(sock, opt, unsafe.Pointer(&buf[0]), &n); e != nil {
+		return nil, e
+	}
+	r := make([]uint8, attr.number)
+	copy(r, buf[l:n])
+	return r, nil
+}
+
+// SetAuthKey set the shared key of number n for the association.
+func (c *SCTPConn) SetAuthKey(n uint16, key []byte) error {
+	if e := setAuthKey(c.sock(), c.id, n, key); e != nil {
+		return &net.OpError{
+			Op:     "setauthkey",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   c.RemoteAddr(),
+			Err:    e}
+	}
+	return nil
+}
+
+// ActivateAuthKey makes the shared key of number n active
+// for the association.
+func (c *SCTPConn) ActivateAuthKey(n uint16) error {
+	if e := setAuthKeyID(c.sock(), sctpAuthActiveKey, c.id, n); e != nil {
+		return &net.OpError{
+			Op:     "activateauthkey",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   c.RemoteAddr(),
+			Err:    e}
+	}
+	return nil
+}
+
+// DeleteAuthKey deletes the shared key of number n from the association.
+// Active key can not be deleted.
+func (c *SCTPConn) DeleteAuthKey(n uint16) error {
+	e := setAuthKeyID(c.sock(), sctpAuthDeactivateKey, c.id, n)
+	if e == nil {
+		e = setAuthKeyID(c.sock(), sctpAuthDeleteKey, c.id, n)
+	}
+	if e != nil {
+		return &net.OpError{
+			Op:     "deleteauthkey",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   c.RemoteAddr(),
+			Err:    e}
+	}
+	return nil
+}
+
+// PeerAuthChunks returns chunk types that the peer requires authentication.
+func (c *SCTPConn) PeerAuthChunks() ([]uint8, error) {
+	r, e := getAuthChunks(c.sock(), sctpPeerAuthChunks, c.id)
+	if e != nil {
+		return nil, &net.OpError{
+			Op:     "getauthchunks",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   c.RemoteAddr(),
+			Err:    e}
+	}
+	return r, nil
+}
+
+// LocalAuthChunks returns chunk types that the local endpoint
+// requires authentication.
+func (c *SCTPConn) LocalAuthChunks() ([]uint8, error) {
+	r, e := getAuthChunks(c.sock(), sctpLocalAuthChunks, c.id)
+	if e != nil {
+		return nil, &net.OpError{
+			Op:     "getauthchunks",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   c.RemoteAddr(),
+			Err:    e}
+	}
+	return r, nil
+}
+
+// SetAuthKey set the shared key of number n for the endpoint,
+// the key is used for new associations.
+func (l *SCTPListener) SetAuthKey(n uint16, key []byte) error {
+	if e := setAuthKey(l.sock(), 0, n, key); e != nil {
+		return &net.OpError{
+			Op:     "setauthkey",
+			Net:    "sctp",
+			Source: l.Addr(),
+			Err:    e}
+	}
+	return nil
+}
+
+// ActivateAuthKey makes the shared key of number n active
+// for the endpoint.
+func (l *SCTPListener) ActivateAuthKey(n uint16) error {
+	if e := setAuthKeyID(l.sock(), sctpAuthActiveKey, 0, n); e != nil {
+		return &net.OpError{
+			Op:     "activateauthkey",
+			Net:    "sctp",
+			Source: l.Addr(),
+			Err:    e}
+	}
+	return nil
+}
+
+// DeleteAuthKey deletes the shared key of number n from the endpoint.
+// Active key can not be deleted.
+func (l *SCTPListener) DeleteAuthKey(n uint16) error {
+	e := setAuthKeyID(l.sock(), sctpAuthDeactivateKey, 0, n)
+	if e == nil {
+		e = setAuthKeyID(l.sock(), sctpAuthDeleteKey, 0, n)
+	}
+	if e != nil {
+		return &net.OpError{
+			Op:     "deleteauthkey",
+			Net:    "sctp",
+			Source: l.Addr(),
+			Err:    e}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package extnet
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAuth(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	chunks := []uint8{ChunkASCONF, ChunkASCONFAck}
+	d0 := &SCTPDialer{
+		LocalAddr:  a0,
+		AuthChunks: chunks,
+		HMACIdents: []uint16{HMACSHA256, HMACSHA1}}
+	l0a, e := d0.Listen()
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+	l0 := l0a.(*SCTPListener)
+
+	d1 := &SCTPDialer{
+		LocalAddr:  a1,
+		AuthChunks: chunks}
+	c1a, e := d1.Dial("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+	c1 := c1a.(*SCTPConn)
+
+	_, e = l0.AcceptSCTP()
+	if e != nil {
+		t.Errorf("accept faied: %s", e)
+	}
+
+	if c, e := c1.PeerAuthChunks(); e != nil {
+		t.Errorf("get peer auth chunks failed: %s", e)
+	} else {
+		for _, i := range chunks {
+			if bytes.IndexByte(c, i) < 0 {
+				t.Errorf("chunk %x is not authenticated by peer", i)
+			}
+		}
+	}
+	if c, e := c1.LocalAuthChunks(); e != nil {
+		t.Errorf("get local auth chunks failed: %s", e)
+	} else if len(c) < len(chunks) {
+		t.Errorf("local auth chunks %x is not enough", c)
+	}
+
+	if e = c1.SetAuthKey(1, []byte("test key")); e != nil {
+		t.Errorf("set auth key failed: %s", e)
+	}
+	if e = c1.DeleteAuthKey(1); e != nil {
+		t.Errorf("delete auth key failed: %s", e)
+	}
+	if e = c1.ActivateAuthKey(5); e == nil {
+		t.Errorf("unknown auth key must not be activated")
+	}
+
+	if e = l0.SetAuthKey(1, []byte("test key")); e != nil {
+		t.Errorf("set auth key failed: %s", e)
+	}
+	if e = l0.ActivateAuthKey(1); e != nil {
+		t.Errorf("activate auth key failed: %s", e)
+	}
+
+	e = c1.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+
+	e = l0.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
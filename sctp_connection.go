@@ -1,6 +1,8 @@
 package extnet
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -11,11 +13,15 @@ import (
 
 // SCTPConn is an implementation of the Conn interface for SCTP network connections.
 type SCTPConn struct {
-	l  *SCTPListener
-	id assocT
+	sctpSocket
+	l *SCTPListener
 
-	buf, win []byte
-	err      error
+	rcv     []*message
+	part    map[partKey]*message
+	err     error
+	rclosed bool
+	cerr    *AssocError
+	timeout time.Duration
 
 	m, rm, wm sync.Mutex
 	wc        sync.Cond
@@ -23,81 +29,262 @@ type SCTPConn struct {
 	wd, rd time.Time
 }
 
+// MessageInfo is the attribute of a received SCTP message.
+type MessageInfo struct {
+	Stream  uint16
+	SSN     uint16
+	Flags   uint16
+	PPID    uint32
+	Context uint32
+	TSN     uint32
+	AssocID int
+}
+
+// Unordered returns true if the message was sent as unordered.
+func (i *MessageInfo) Unordered() bool {
+	return i.Flags&sctpUnordered == sctpUnordered
+}
+
+type message struct {
+	b    []byte
+	info MessageInfo
+}
+
+// partKey identifies partially delivered message,
+// messages of different streams may be interleaved.
+type partKey struct {
+	stream    uint16
+	unordered bool
+}
+
 func (c *SCTPConn) Read(b []byte) (n int, e error) {
 	c.rm.Lock()
 	defer c.rm.Unlock()
 	c.m.Lock()
 	defer c.m.Unlock()
 
-	var t *time.Timer
-	if now := time.Now(); !c.rd.IsZero() && now.Before(c.rd) {
-		t = time.AfterFunc(c.rd.Sub(now), func() {
-			er := &net.OpError{
-				Op:     "read",
-				Net:    "sctp",
-				Source: c.LocalAddr(),
-				Addr:   c.RemoteAddr(),
-				Err:    &timeoutError{}}
-			c.queue(nil, er)
-		})
+	m, e := c.next(context.Background())
+	if e != nil {
+		return
+	}
+	n = copy(b, m.b)
+	m.b = m.b[n:]
+	if len(m.b) == 0 {
+		c.rcv = c.rcv[1:]
+	}
+	return
+}
+
+// ReadMsg reads one whole SCTP message and returns its attributes.
+// If b is too small for the message, io.ErrShortBuffer is returned
+// and the message is kept for the next read.
+func (c *SCTPConn) ReadMsg(b []byte) (n int, info *MessageInfo, e error) {
+	return c.ReadMsgContext(context.Background(), b)
+}
+
+// ReadMsgContext is same as ReadMsg but waits the message
+// until the context is done.
+func (c *SCTPConn) ReadMsgContext(ctx context.Context, b []byte) (n int, info *MessageInfo, e error) {
+	c.rm.Lock()
+	defer c.rm.Unlock()
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	defer c.watch(ctx)()
+
+	m, e := c.next(ctx)
+	if e != nil {
+		return
 	}
+	if len(b) < len(m.b) {
+		e = io.ErrShortBuffer
+		return
+	}
+	n = copy(b, m.b)
+	c.rcv = c.rcv[1:]
+	info = &MessageInfo{}
+	*info = m.info
+	return
+}
+
+// wakeup wakes the reader up to check the context and the deadline.
+func (c *SCTPConn) wakeup() {
+	c.m.Lock()
+	c.wc.Broadcast()
+	c.m.Unlock()
+}
+
+// watch wakes the reader up when ctx is done,
+// returned function must be called after waiting.
+func (c *SCTPConn) watch(ctx context.Context) func() {
+	done := ctx.Done()
+	if done == nil {
+		return func() {}
+	}
+	stop := make(chan bool)
+	go func() {
+		select {
+		case <-done:
+			c.wakeup()
+		case <-stop:
+		}
+	}()
+	return func() { close(stop) }
+}
+
+// next waits a received message until ctx is done or the read deadline
+// is exceeded, c.m must be locked.
+func (c *SCTPConn) next(ctx context.Context) (*message, error) {
+	// timer wakes the reader up at the read deadline
+	var t *time.Timer
+	var d time.Time
+	defer func() {
+		if t != nil {
+			t.Stop()
+		}
+	}()
 
 	for {
-		if len(c.win) != 0 {
-			n = copy(b, c.win)
-			c.win = c.win[n:]
-			break
+		if c.rclosed {
+			return nil, io.EOF
+		}
+		if len(c.rcv) != 0 {
+			return c.rcv[0], nil
 		}
 		if c.err != nil {
-			e = c.err
+			e := c.err
 			if e != io.EOF {
 				c.err = nil
+			} else if c.cerr != nil && c.cerr.Err != ErrShutdown {
+				e = c.cerr
+			}
+			return nil, e
+		}
+		var e error
+		if e = ctx.Err(); e == nil && !c.rd.IsZero() {
+			if now := time.Now(); !now.Before(c.rd) {
+				e = &timeoutError{}
+			} else if t == nil || !d.Equal(c.rd) {
+				if t != nil {
+					t.Stop()
+				}
+				d = c.rd
+				t = time.AfterFunc(d.Sub(now), c.wakeup)
 			}
-			break
+		}
+		if e != nil {
+			return nil, &net.OpError{
+				Op:     "read",
+				Net:    "sctp",
+				Source: c.LocalAddr(),
+				Addr:   c.RemoteAddr(),
+				Err:    e}
 		}
 		c.wc.Wait()
 	}
-
-	if t != nil {
-		t.Stop()
-	}
-	return
 }
 
-func (c *SCTPConn) queue(b []byte, e error) error {
+// queue stores received data, the message is completed when eor is true.
+func (c *SCTPConn) queue(b []byte, info *sndrcvInfo, eor bool) error {
 	if c.err == io.EOF {
 		return c.err
 	}
 
 	c.wm.Lock()
 	defer c.wm.Unlock()
+	if c.rclosed {
+		return nil
+	}
 
-	if b != nil {
-		c.m.Lock()
-		defer c.m.Unlock()
-
-		if len(c.win) == 0 {
-			c.win = append(c.buf, b...)
-		} else {
-			c.win = append(c.win, b...)
+	k := partKey{
+		stream:    info.stream,
+		unordered: info.flags&sctpUnordered == sctpUnordered}
+	m, ok := c.part[k]
+	if !ok {
+		m = &message{info: MessageInfo{
+			Stream:  info.stream,
+			SSN:     info.ssn,
+			Flags:   info.flags,
+			PPID:    info.ppid,
+			Context: info.context,
+			TSN:     info.tsn,
+			AssocID: int(info.assocID)}}
+		if c.part == nil {
+			c.part = make(map[partKey]*message)
 		}
-		c.wc.Signal()
+		c.part[k] = m
 	}
+	m.b = append(m.b, b...)
+	if !eor {
+		return nil
+	}
+	delete(c.part, k)
 
-	if e != nil {
-		c.m.Lock()
-		defer c.m.Unlock()
+	c.m.Lock()
+	defer c.m.Unlock()
 
-		c.err = e
-		c.wc.Signal()
+	c.rcv = append(c.rcv, m)
+	c.wc.Broadcast()
+	return nil
+}
+
+// discard drops the partially delivered message of the stream s
+// that is aborted. The stream is not notified without interleaving,
+// but only one message can be partially delivered in that case.
+func (c *SCTPConn) discard(s uint16, unordered bool) {
+	c.wm.Lock()
+	defer c.wm.Unlock()
+
+	k := partKey{stream: s, unordered: unordered}
+	if _, ok := c.part[k]; ok || len(c.part) != 1 {
+		delete(c.part, k)
+		return
+	}
+	for k := range c.part {
+		delete(c.part, k)
 	}
+}
+
+// closeAssoc stores the reason of closing the association,
+// then the reader gets io.EOF or e.
+func (c *SCTPConn) closeAssoc(e *AssocError) {
+	c.m.Lock()
+	if c.cerr == nil {
+		c.cerr = e
+	}
+	c.m.Unlock()
+	c.fail(io.EOF)
+}
+
+// closed returns true if the association is closed.
+func (c *SCTPConn) closed() bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.err == io.EOF
+}
+
+// fail stores the error that is returned to the reader.
+func (c *SCTPConn) fail(e error) error {
+	if c.err == io.EOF {
+		return c.err
+	}
+
+	c.wm.Lock()
+	defer c.wm.Unlock()
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.err = e
+	c.wc.Broadcast()
 	return nil
 }
 
 func (c *SCTPConn) Write(b []byte) (int, error) {
 	buf := make([]byte, len(b))
 	copy(buf, b)
-	n, e := c.send(buf, c.l.ppid, 0, 0)
+	n, e := c.send(buf, sndrcvInfo{
+		ppid:  c.l.ppid,
+		flags: c.l.uo}, nil)
 	if e != nil {
 		e = &net.OpError{
 			Op:     "write",
@@ -113,7 +300,64 @@ func (c *SCTPConn) Write(b []byte) (int, error) {
 func (c *SCTPConn) WriteToStream(b []byte, s uint16, i uint32) (int, error) {
 	buf := make([]byte, len(b))
 	copy(buf, b)
-	n, e := c.send(buf, i, s, 0)
+	n, e := c.send(buf, sndrcvInfo{
+		stream: s,
+		ppid:   i,
+		flags:  c.l.uo}, nil)
+	if e != nil {
+		e = &net.OpError{
+			Op:     "write",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   c.RemoteAddr(),
+			Err:    e}
+	}
+	return n, e
+}
+
+// SendInfo is the attribute of a SCTP message to send.
+type SendInfo struct {
+	Stream          uint16
+	PPID            uint32
+	Unordered       bool
+	Context         uint32
+	TimeToLive      time.Duration
+	SackImmediately bool
+	// Addr overrides the primary destination address of the message,
+	// it must be one of the peer addresses of the association.
+	Addr net.IP
+	// PR is the partial reliability policy of the message,
+	// TimeToLive is ignored if PR is not nil.
+	PR *PRInfo
+}
+
+// WriteMsg write data with attribute specified by info.
+// If info is nil, it is same as Write.
+func (c *SCTPConn) WriteMsg(b []byte, info *SendInfo) (int, error) {
+	if info == nil {
+		return c.Write(b)
+	}
+
+	buf := make([]byte, len(b))
+	copy(buf, b)
+	s := sndrcvInfo{
+		stream:     info.Stream,
+		ppid:       info.PPID,
+		context:    info.Context,
+		timetolive: uint32(info.TimeToLive / time.Millisecond)}
+	if info.PR != nil {
+		s.flags |= uint16(info.PR.Policy)
+		s.timetolive = info.PR.Value
+	} else if s.timetolive != 0 {
+		s.flags |= sctpPrSctpTTL
+	}
+	if info.Unordered {
+		s.flags |= sctpUnordered
+	}
+	if info.SackImmediately {
+		s.flags |= sctpSackImmediately
+	}
+	n, e := c.send(buf, s, info.Addr)
 	if e != nil {
 		e = &net.OpError{
 			Op:     "write",
@@ -126,8 +370,66 @@ func (c *SCTPConn) WriteToStream(b []byte, s uint16, i uint32) (int, error) {
 }
 
 // Close closes the connection.
+// Close waits completion of the shutdown until the timeout
+// specified by SetCloseTimeout, then aborts the association.
+// Error is returned if received data is not read and discarded.
 func (c *SCTPConn) Close() error {
-	_, e := c.send([]byte{}, 0, 0, sctpEoF)
+	d := c.timeout
+	switch {
+	case d < 0:
+		return c.Abort("close")
+	case d == 0:
+		d = DefaultCloseTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return c.CloseContext(ctx)
+}
+
+// CloseContext closes the connection and waits completion of
+// the shutdown until the context is done.
+// The association is aborted if the context is done before completion.
+func (c *SCTPConn) CloseContext(ctx context.Context) error {
+	e := c.CloseWrite()
+	discarded, de := c.drain(ctx)
+	switch {
+	case de != nil:
+		c.Abort("close")
+		e = de
+	case e != nil:
+		return e
+	case discarded:
+		e = errors.New("unread data is discarded")
+	default:
+		return nil
+	}
+	return &net.OpError{
+		Op:     "close",
+		Net:    "sctp",
+		Source: c.LocalAddr(),
+		Addr:   c.RemoteAddr(),
+		Err:    e}
+}
+
+// SetCloseTimeout sets the timeout of Close.
+// If d is 0, DefaultCloseTimeout is used.
+// If d is negative, Close aborts the association immediately.
+// CloseContext can wait the shutdown without timeout.
+func (c *SCTPConn) SetCloseTimeout(d time.Duration) error {
+	c.timeout = d
+	return nil
+}
+
+// CloseWrite starts shutdown of the association.
+// Data that is already received can be read until the shutdown is completed,
+// then Read returns io.EOF.
+func (c *SCTPConn) CloseWrite() (e error) {
+	// SCTP_EOF is not available on one-to-one style socket
+	if c.l.oneToOne {
+		e = sockShutdown(c.sock())
+	} else {
+		_, e = c.send([]byte{}, sndrcvInfo{flags: sctpEoF}, nil)
+	}
 	if e != nil {
 		e = &net.OpError{
 			Op:     "close",
@@ -136,21 +438,65 @@ func (c *SCTPConn) Close() error {
 			Addr:   c.RemoteAddr(),
 			Err:    e}
 	}
-	b := make([]byte, 1024)
+	return e
+}
+
+// CloseRead discards received data and further inbound data,
+// then Read returns io.EOF.
+func (c *SCTPConn) CloseRead() error {
+	c.wm.Lock()
+	defer c.wm.Unlock()
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.rclosed = true
+	c.rcv = nil
+	c.part = nil
+	c.wc.Broadcast()
+	return nil
+}
+
+// drain discards received messages until the association is closed
+// or ctx is done. It returns true if any message is discarded.
+// c.rm is not locked, then blocked readers do not block drain.
+func (c *SCTPConn) drain(ctx context.Context) (bool, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	defer c.watch(ctx)()
+
+	discarded := false
 	for {
-		_, eof := c.Read(b)
-		if eof == io.EOF {
-			break
+		if len(c.rcv) != 0 {
+			discarded = true
+			c.rcv = nil
+		}
+		if c.err == io.EOF {
+			return discarded, nil
 		}
+		if e := ctx.Err(); e != nil {
+			return discarded, e
+		}
+		c.wc.Wait()
 	}
-	return e
 }
 
 // Abort closes the connection with abort message.
-func (c *SCTPConn) Abort(reason string) error {
-	buf := make([]byte, len([]byte(reason)))
-	copy(buf, []byte(reason))
-	_, e := c.send(buf, 0, 0, sctpAbort)
+// reason is not sent on one-to-one style socket, the socket is closed
+// with zero linger time instead.
+func (c *SCTPConn) Abort(reason string) (e error) {
+	if c.l.oneToOne {
+		if e = sockAbort(c.sock()); e == nil {
+			c.closeAssoc(&AssocError{
+				Err:    ErrAborted,
+				Cause:  CauseUserInitiatedAbort,
+				Reason: reason})
+			e = c.file().close()
+		}
+	} else {
+		buf := make([]byte, len([]byte(reason)))
+		copy(buf, []byte(reason))
+		_, e = c.send(buf, sndrcvInfo{flags: sctpAbort}, nil)
+	}
 	if e != nil {
 		e = &net.OpError{
 			Op:     "abort",
@@ -162,35 +508,70 @@ func (c *SCTPConn) Abort(reason string) error {
 	return e
 }
 
-func (c *SCTPConn) send(b []byte, p uint32, s, f uint16) (int, error) {
-	info := sndrcvInfo{}
-	if n := time.Now(); !c.wd.IsZero() && n.Before(c.wd) {
-		info.timetolive = uint32(c.wd.Sub(n))
+// defaultPR returns true if the default partial reliability policy is set.
+func (c *SCTPConn) defaultPR() bool {
+	p, e := c.DefaultPRInfo()
+	return e == nil && p.Policy != PRNone
+}
+
+// send data to the association, destination address is overridden by to.
+func (c *SCTPConn) send(b []byte, info sndrcvInfo, to net.IP) (int, error) {
+	c.m.Lock()
+	wd := c.wd
+	c.m.Unlock()
+
+	// message is abandoned when write deadline is exceeded,
+	// unless the default partial reliability policy is set
+	if n := time.Now(); info.flags&(sctpPrSctpMask|sctpEoF|sctpAbort) == 0 &&
+		!wd.IsZero() && n.Before(wd) && !c.defaultPR() {
+		info.flags |= sctpPrSctpTTL
+		info.timetolive = uint32((wd.Sub(n) + time.Millisecond - 1) / time.Millisecond)
 	}
-	info.stream = s
-	info.flags = f | c.l.uo
 	info.assocID = c.id
-	info.ppid = p
 
-	i, e := sctpSend(c.l.sock, b, &info, 0)
-	if Notificator != nil {
-		if i < 0 {
-			i = 0
+	var i int
+	var e error
+	if to == nil {
+		e = c.file().write(wd, func(fd int) (e error) {
+			i, e = sctpSend(fd, b, &info, 0)
+			return
+		})
+	} else if ra, ok := c.RemoteAddr().(*SCTPAddr); !ok {
+		i, e = -1, errors.New("remote address is not available")
+	} else if n := ra.index(to); n < 0 {
+		i, e = -1, errors.New("address is not a peer address of the association")
+	} else {
+		info.flags |= sctpAddrOver
+		sa := rawSockaddr(to, ra.zone(n), ra.Port)
+		e = c.file().write(wd, func(fd int) (e error) {
+			i, e = sctpSendmsg(fd, b, unsafe.Pointer(&sa), sockaddrLen(to), &info)
+			return
+		})
+	}
+	if e != nil {
+		c.m.Lock()
+		if c.cerr != nil {
+			e = c.cerr
 		}
-		Notificator(&SctpSendData{
-			ID:        int(info.assocID),
-			Stream:    int(info.stream),
-			PPID:      int(info.ppid),
-			Unordered: info.flags&sctpUnordered == sctpUnordered,
-			Data:      b[:i],
-			Err:       e})
+		c.m.Unlock()
 	}
+	if i < 0 {
+		i = 0
+	}
+	c.l.notify(&SctpSendData{
+		ID:        int(info.assocID),
+		Stream:    int(info.stream),
+		PPID:      int(info.ppid),
+		Unordered: info.flags&sctpUnordered == sctpUnordered,
+		Data:      b[:i],
+		Err:       e})
 	return i, e
 }
 
 // SctpSendData is the error type that indicate
 // send data to the association.
 type SctpSendData struct {
+	EventTime
 	ID        int
 	Stream    int
 	PPID      int
@@ -222,9 +603,66 @@ func (e *SctpSendData) Error() string {
 		e.ID, e.Stream, s, uo, e.Data)
 }
 
+func (e *SctpSendData) AssocID() int {
+	return e.ID
+}
+
+// PeelOff moves the association to own socket, then data of
+// the association is received by dedicated handler.
+func (c *SCTPConn) PeelOff() error {
+	if c.l.oneToOne || c.sock() != c.l.sock() {
+		return &net.OpError{
+			Op:     "peeloff",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   c.RemoteAddr(),
+			Err:    errors.New("association already has own socket")}
+	}
+
+	sock, e := sctpPeeloff(c.l.sock(), c.id)
+	var f *sockFile
+	if e == nil {
+		if f, e = newSockFile(sock); e != nil {
+			sockClose(sock)
+		}
+	}
+	if e != nil {
+		return &net.OpError{
+			Op:     "peeloff",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   c.RemoteAddr(),
+			Err:    e}
+	}
+	c.setFile(f)
+
+	r := make(chan bool)
+	go read(c.l, f, r)
+	<-r
+
+	// stop handler of dialed socket, no association remains on it
+	if c.l.isClosed() && c.l.count(c.l.sock()) == 0 {
+		c.l.file().close()
+	}
+	return nil
+}
+
+// AddLocalAddr adds local addresses to the socket of the connection.
+// All associations on the socket are affected if the connection
+// shares the socket with the listener, see AddLocalAddr of SCTPListener.
+func (c *SCTPConn) AddLocalAddr(addr *SCTPAddr) error {
+	return c.bindx("addaddr", addr, sctpBindxAddAddr)
+}
+
+// RemoveLocalAddr removes local addresses from the socket of the connection,
+// see AddLocalAddr.
+func (c *SCTPConn) RemoveLocalAddr(addr *SCTPAddr) error {
+	return c.bindx("removeaddr", addr, sctpBindxRemAddr)
+}
+
 // LocalAddr returns the local network address.
 func (c *SCTPConn) LocalAddr() net.Addr {
-	ptr, n, e := sctpGetladdrs(c.l.sock, c.id)
+	ptr, n, e := sctpGetladdrs(c.sock(), c.id)
 	if e != nil {
 		return nil
 	}
@@ -234,7 +672,7 @@ func (c *SCTPConn) LocalAddr() net.Addr {
 
 // RemoteAddr returns the remote network address.
 func (c *SCTPConn) RemoteAddr() net.Addr {
-	ptr, n, e := sctpGetpaddrs(c.l.sock, c.id)
+	ptr, n, e := sctpGetpaddrs(c.sock(), c.id)
 	if e != nil {
 		return nil
 	}
@@ -242,107 +680,694 @@ func (c *SCTPConn) RemoteAddr() net.Addr {
 	return resolveFromRawAddr(ptr, n)
 }
 
-// SetDeadline implements the Conn SetDeadline method.
-func (c *SCTPConn) SetDeadline(t time.Time) (e error) {
-	e = c.SetReadDeadline(t)
+// PathState is the state of the peer address.
+type PathState int
+
+// PathState values
+const (
+	PathInactive          PathState = sctpInactive
+	PathPotentiallyFailed PathState = sctpPf
+	PathActive            PathState = sctpActive
+	PathUnconfirmed       PathState = sctpUnconfirmed
+)
+
+func (s PathState) String() string {
+	switch s {
+	case PathInactive:
+		return "inactive"
+	case PathPotentiallyFailed:
+		return "potentially-failed"
+	case PathActive:
+		return "active"
+	case PathUnconfirmed:
+		return "unconfirmed"
+	}
+	return "unknown"
+}
+
+// PathInfo is the status of the peer address.
+type PathInfo struct {
+	IP    net.IP
+	State PathState
+	Cwnd  int
+	SRTT  time.Duration
+	RTO   time.Duration
+	MTU   int
+}
+
+func (i *paddrinfo) pathInfo() PathInfo {
+	return PathInfo{
+		IP:    ipFromSockaddr(unsafe.Pointer(&i.addr)),
+		State: PathState(i.state),
+		Cwnd:  int(i.cwnd),
+		SRTT:  time.Duration(i.srtt) * time.Millisecond,
+		RTO:   time.Duration(i.rto) * time.Millisecond,
+		MTU:   int(i.mtu)}
+}
+
+// Paths returns status of each peer address.
+func (c *SCTPConn) Paths() ([]PathInfo, error) {
+	ra, ok := c.RemoteAddr().(*SCTPAddr)
+	if !ok {
+		return nil, &net.OpError{
+			Op:     "getpaths",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Err:    errors.New("remote address is not available")}
+	}
+
+	r := make([]PathInfo, 0, len(ra.IP))
+	for n, ip := range ra.IP {
+		attr := paddrinfo{
+			assocID: c.id,
+			addr:    rawSockaddr(ip, ra.zone(n), ra.Port)}
+		l := unsafe.Sizeof(attr)
+		p := unsafe.Pointer(&attr)
+
+		if e := getSockOpt(c.sock(), sctpGetPeerAddrInfo, p, &l); e != nil {
+			return nil, &net.OpError{
+				Op:     "getpaths",
+				Net:    "sctp",
+				Source: c.LocalAddr(),
+				Addr:   ra,
+				Err:    e}
+		}
+		r = append(r, attr.pathInfo())
+	}
+	return r, nil
+}
+
+// PrimaryAddr returns the primary destination address of the association.
+func (c *SCTPConn) PrimaryAddr() (net.IP, error) {
+	attr := primAddr{assocID: c.id}
+	l := unsafe.Sizeof(attr)
+	p := unsafe.Pointer(&attr)
+
+	if e := getSockOpt(c.sock(), sctpPrimaryAddr, p, &l); e != nil {
+		return nil, &net.OpError{
+			Op:     "getprimary",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   c.RemoteAddr(),
+			Err:    e}
+	}
+	return ipFromSockaddr(unsafe.Pointer(&attr.addr)), nil
+}
+
+// SetPrimaryAddr set the primary destination address of the association.
+// ip must be one of the peer address.
+func (c *SCTPConn) SetPrimaryAddr(ip net.IP) error {
+	ra, ok := c.RemoteAddr().(*SCTPAddr)
+	n := -1
+	if ok {
+		n = ra.index(ip)
+	}
+	if n < 0 {
+		return &net.OpError{
+			Op:     "setprimary",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   &net.IPAddr{IP: ip},
+			Err:    errors.New("address is not a peer address of the association")}
+	}
+
+	attr := primAddr{
+		assocID: c.id,
+		addr:    rawSockaddr(ip, ra.zone(n), ra.Port)}
+	l := unsafe.Sizeof(attr)
+	p := unsafe.Pointer(&attr)
+
+	if e := setSockOpt(c.sock(), sctpPrimaryAddr, p, l); e != nil {
+		return &net.OpError{
+			Op:     "setprimary",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   &net.IPAddr{IP: ip},
+			Err:    e}
+	}
+	return nil
+}
+
+// RequestPeerPrimary requests the peer to use ip as its primary destination.
+// ip must be one of the local address, and the peer must support ASCONF.
+func (c *SCTPConn) RequestPeerPrimary(ip net.IP) error {
+	la, ok := c.LocalAddr().(*SCTPAddr)
+	n := -1
+	if ok {
+		n = la.index(ip)
+	}
+	if n < 0 {
+		return &net.OpError{
+			Op:     "setpeerprimary",
+			Net:    "sctp",
+			Source: &net.IPAddr{IP: ip},
+			Addr:   c.RemoteAddr(),
+			Err:    errors.New("address is not a local address of the association")}
+	}
+
+	attr := primAddr{
+		assocID: c.id,
+		addr:    rawSockaddr(ip, la.zone(n), la.Port)}
+	l := unsafe.Sizeof(attr)
+	p := unsafe.Pointer(&attr)
+
+	if e := setSockOpt(c.sock(), sctpSetPeerPrimaryAddr, p, l); e != nil {
+		return &net.OpError{
+			Op:     "setpeerprimary",
+			Net:    "sctp",
+			Source: &net.IPAddr{IP: ip},
+			Addr:   c.RemoteAddr(),
+			Err:    e}
+	}
+	return nil
+}
+
+// PeerAddrFlags is the flags of PeerAddrParams.
+type PeerAddrFlags uint32
+
+// PeerAddrFlags values
+const (
+	HbEnable         PeerAddrFlags = sppHbEnable
+	HbDisable        PeerAddrFlags = sppHbDisable
+	HbDemand         PeerAddrFlags = sppHbDemand
+	PmtudEnable      PeerAddrFlags = sppPmtudEnable
+	PmtudDisable     PeerAddrFlags = sppPmtudDisable
+	SackDelayEnable  PeerAddrFlags = sppSackdelayEnable
+	SackDelayDisable PeerAddrFlags = sppSackdelayDisable
+	HbTimeIsZero     PeerAddrFlags = sppHbTimeIsZero
+)
+
+// PeerAddrParams is the parameters of the peer address.
+// Zero value of each parameter is not changed by SetPeerAddrParams,
+// heartbeat interval is set to 0 with HbTimeIsZero.
+// PathMTU is used only with PmtudDisable.
+type PeerAddrParams struct {
+	// Addr is the peer address, parameters are for all addresses
+	// of the association if Addr is nil.
+	Addr              net.IP
+	HeartbeatInterval time.Duration
+	PathMaxRetrans    int
+	PathMTU           int
+	SackDelay         time.Duration
+	Flags             PeerAddrFlags
+}
+
+type paddrparams struct {
+	assocID    assocT
+	addr       sockaddrStorage
+	hbinterval uint32
+	pathmaxrxt uint16
+	pathmtu    uint32
+	sackdelay  uint32
+	flags      uint32
+}
+
+func (p *PeerAddrParams) raw(id assocT, a *SCTPAddr) paddrparams {
+	r := paddrparams{
+		assocID:    id,
+		hbinterval: uint32(p.HeartbeatInterval / time.Millisecond),
+		pathmaxrxt: uint16(p.PathMaxRetrans),
+		pathmtu:    uint32(p.PathMTU),
+		sackdelay:  uint32(p.SackDelay / time.Millisecond),
+		flags:      uint32(p.Flags)}
+	if p.Addr != nil {
+		z, port := "", 0
+		if a != nil {
+			if n := a.index(p.Addr); n >= 0 {
+				z = a.zone(n)
+			}
+			port = a.Port
+		}
+		r.addr = rawSockaddr(p.Addr, z, port)
+	}
+	return r
+}
+
+func setPeerAddrParams(sock int, p paddrparams) error {
+	b := p.marshal()
+	return setSockOpt(sock, sctpPeerAddrParams, unsafe.Pointer(&b[0]), uintptr(len(b)))
+}
+
+// PeerAddrParams returns the parameters of the peer address ip,
+// or the parameters of the association if ip is nil.
+func (c *SCTPConn) PeerAddrParams(ip net.IP) (*PeerAddrParams, error) {
+	ra, _ := c.RemoteAddr().(*SCTPAddr)
+	r := (&PeerAddrParams{Addr: ip}).raw(c.id, ra)
+	b := r.marshal()
+	l := uintptr(len(b))
+
+	if e := getSockOpt(c.sock(), sctpPeerAddrParams, unsafe.Pointer(&b[0]), &l); e != nil {
+		return nil, &net.OpError{
+			Op:     "getpeeraddrparams",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   ra,
+			Err:    e}
+	}
+	r.unmarshal(b)
+	return &PeerAddrParams{
+		Addr:              ip,
+		HeartbeatInterval: time.Duration(r.hbinterval) * time.Millisecond,
+		PathMaxRetrans:    int(r.pathmaxrxt),
+		PathMTU:           int(r.pathmtu),
+		SackDelay:         time.Duration(r.sackdelay) * time.Millisecond,
+		Flags:             PeerAddrFlags(r.flags)}, nil
+}
+
+// SetPeerAddrParams set the parameters of the peer address p.Addr,
+// or the parameters of all peer addresses if p.Addr is nil.
+func (c *SCTPConn) SetPeerAddrParams(p PeerAddrParams) error {
+	ra, _ := c.RemoteAddr().(*SCTPAddr)
+	if e := setPeerAddrParams(c.sock(), p.raw(c.id, ra)); e != nil {
+		return &net.OpError{
+			Op:     "setpeeraddrparams",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   ra,
+			Err:    e}
+	}
+	return nil
+}
+
+// ResetStreams requests to reset the incoming streams in and
+// the outgoing streams out.
+// All streams of the both direction are reset if in and out are empty.
+// If both in and out are specified, they must be the same streams
+// because only one request can be outstanding at a time.
+// Stream reconfiguration must be enabled with SCTPDialer.StreamReset.
+func (c *SCTPConn) ResetStreams(in, out []uint16) (e error) {
+	switch {
+	case len(in) == 0 && len(out) == 0:
+		e = c.resetStreams(sctpStreamResetIn|sctpStreamResetOut, nil)
+	case len(out) == 0:
+		e = c.resetStreams(sctpStreamResetIn, in)
+	case len(in) == 0:
+		e = c.resetStreams(sctpStreamResetOut, out)
+	case sameStreams(in, out):
+		e = c.resetStreams(sctpStreamResetIn|sctpStreamResetOut, in)
+	default:
+		e = errors.New("different streams of both direction can not be reset at once")
+	}
+
 	if e != nil {
-		return
+		e = &net.OpError{
+			Op:     "resetstreams",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   c.RemoteAddr(),
+			Err:    e}
 	}
-	e = c.SetWriteDeadline(t)
 	return
 }
 
-// SetReadDeadline implements the Conn SetReadDeadline method.
-func (c *SCTPConn) SetReadDeadline(t time.Time) error {
-	c.rd = t
-	return nil
+func sameStreams(a, b []uint16) bool {
+	m := make(map[uint16]bool, len(a))
+	for _, s := range a {
+		m[s] = true
+	}
+	for _, s := range b {
+		if !m[s] {
+			return false
+		}
+		delete(m, s)
+	}
+	return len(m) == 0
 }
 
-// SetWriteDeadline implements the Conn SetWriteDeadline method.
-func (c *SCTPConn) SetWriteDeadline(t time.Time) error {
-	c.wd = t
+func (c *SCTPConn) resetStreams(flags uint16, s []uint16) error {
+	type opt struct {
+		assocID assocT
+		flags   uint16
+		number  uint16
+	}
+	l := unsafe.Sizeof(opt{})
+	buf := make([]byte, l+uintptr(len(s))*2)
+
+	attr := (*opt)(unsafe.Pointer(&buf[0]))
+	attr.assocID = c.id
+	attr.flags = flags
+	attr.number = uint16(len(s))
+	for i, id := range s {
+		*(*uint16)(unsafe.Pointer(&buf[l+uintptr(i)*2])) = id
+	}
+
+	return setSockOpt(c.sock(), sctpResetStreams, unsafe.Pointer(&buf[0]), uintptr(len(buf)))
+}
+
+// ResetAssoc requests to reset TSN and all streams of the association.
+// Stream reconfiguration must be enabled with SCTPDialer.StreamReset.
+func (c *SCTPConn) ResetAssoc() error {
+	attr := c.id
+	l := unsafe.Sizeof(attr)
+	p := unsafe.Pointer(&attr)
+
+	if e := setSockOpt(c.sock(), sctpResetAssoc, p, l); e != nil {
+		return &net.OpError{
+			Op:     "resetassoc",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   c.RemoteAddr(),
+			Err:    e}
+	}
 	return nil
 }
 
-// SetRtoInfo set retransmit timer options
-func (c *SCTPConn) SetRtoInfo(ini, min, max int) error {
+// AddStreams requests to add in incoming streams and out outgoing streams.
+// Stream reconfiguration must be enabled with SCTPDialer.StreamReset.
+func (c *SCTPConn) AddStreams(in, out uint16) error {
 	type opt struct {
 		assocID assocT
-		ini     uint32
-		max     uint32
-		min     uint32
+		in      uint16
+		out     uint16
 	}
 	attr := opt{
 		assocID: c.id,
-		ini:     uint32(ini),
-		max:     uint32(max),
-		min:     uint32(min)}
+		in:      in,
+		out:     out}
 	l := unsafe.Sizeof(attr)
 	p := unsafe.Pointer(&attr)
 
-	return setSockOpt(c.l.sock, sctpRtoInfo, p, l)
+	if e := setSockOpt(c.sock(), sctpAddStreams, p, l); e != nil {
+		return &net.OpError{
+			Op:     "addstreams",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   c.RemoteAddr(),
+			Err:    e}
+	}
+	return nil
 }
 
-// SetAssocinfo set association parameter
-func (c *SCTPConn) SetAssocinfo(pRwnd, lRwnd, cLife, assocMaxRxt, numPeerDest int) error {
-	type opt struct {
-		assocID     assocT
-		pRwnd       uint32
-		lRwnd       uint32
-		cLife       uint32
-		assocMaxRxt uint16
-		numPeerDest uint16
+// PRPolicy is the policy of partially reliable SCTP.
+type PRPolicy uint16
+
+// PRPolicy values
+const (
+	PRNone     PRPolicy = sctpPrSctpNone
+	PRTTL      PRPolicy = sctpPrSctpTTL
+	PRRtx      PRPolicy = sctpPrSctpRtx
+	PRPriority PRPolicy = sctpPrSctpPrio
+)
+
+func (p PRPolicy) String() string {
+	switch p {
+	case PRNone:
+		return "none"
+	case PRTTL:
+		return "ttl"
+	case PRRtx:
+		return "rtx"
+	case PRPriority:
+		return "priority"
 	}
-	attr := opt{
-		assocID:     c.id,
-		pRwnd:       uint32(pRwnd),
-		lRwnd:       uint32(lRwnd),
-		cLife:       uint32(cLife),
-		assocMaxRxt: uint16(assocMaxRxt),
-		numPeerDest: uint16(numPeerDest)}
+	return "unknown"
+}
+
+// PRInfo is the partial reliability policy of messages.
+// Value is lifetime in milliseconds for PRTTL,
+// maximum number of retransmissions for PRRtx,
+// or priority for PRPriority that lower value is higher priority.
+type PRInfo struct {
+	Policy PRPolicy
+	Value  uint32
+}
+
+// PRStatus is the count of abandoned messages.
+type PRStatus struct {
+	AbandonedUnsent uint64
+	AbandonedSent   uint64
+}
+
+type prstatus struct {
+	assocID         assocT
+	sid             uint16
+	policy          uint16
+	abandonedUnsent uint64
+	abandonedSent   uint64
+}
+
+// PRStatus returns the count of abandoned messages of the association.
+func (c *SCTPConn) PRStatus() (*PRStatus, error) {
+	return c.prStatus(sctpPrAssocStatus, 0)
+}
+
+// StreamPRStatus returns the count of abandoned messages of the stream s.
+func (c *SCTPConn) StreamPRStatus(s uint16) (*PRStatus, error) {
+	return c.prStatus(sctpPrStreamStatus, s)
+}
+
+func (c *SCTPConn) prStatus(opt int, s uint16) (*PRStatus, error) {
+	attr := prstatus{
+		assocID: c.id,
+		sid:     s,
+		policy:  sctpPrSctpAll}
 	l := unsafe.Sizeof(attr)
 	p := unsafe.Pointer(&attr)
 
-	return setSockOpt(c.l.sock, sctpAssocInfo, p, l)
+	if e := getSockOpt(c.sock(), opt, p, &l); e != nil {
+		return nil, &net.OpError{
+			Op:     "getprstatus",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   c.RemoteAddr(),
+			Err:    e}
+	}
+	return &PRStatus{
+		AbandonedUnsent: attr.abandonedUnsent,
+		AbandonedSent:   attr.abandonedSent}, nil
+}
+
+// StreamScheduler is the scheduler of outgoing streams.
+type StreamScheduler int
+
+// StreamScheduler values
+const (
+	SchedulerDefault StreamScheduler = iota
+	SchedulerFCFS
+	SchedulerRoundRobin
+	SchedulerPriority
+	SchedulerWFQ
+)
+
+func (s StreamScheduler) String() string {
+	switch s {
+	case SchedulerDefault:
+		return "default"
+	case SchedulerFCFS:
+		return "fcfs"
+	case SchedulerRoundRobin:
+		return "round-robin"
+	case SchedulerPriority:
+		return "priority"
+	case SchedulerWFQ:
+		return "wfq"
+	}
+	return "unknown"
+}
+
+func (s StreamScheduler) value() uint32 {
+	switch s {
+	case SchedulerRoundRobin:
+		return sctpSsRr
+	case SchedulerPriority:
+		return sctpSsPrio
+	case SchedulerWFQ:
+		return sctpSsWfq
+	}
+	return sctpSsFcfs
+}
+
+func schedulerFromValue(v uint32) StreamScheduler {
+	switch v {
+	case sctpSsFcfs:
+		return SchedulerFCFS
+	case sctpSsRr:
+		return SchedulerRoundRobin
+	case sctpSsPrio:
+		return SchedulerPriority
+	case sctpSsWfq:
+		return SchedulerWFQ
+	}
+	return SchedulerDefault
 }
 
-// SetNodelay set delay answer or not
-func (c *SCTPConn) SetNodelay(attr bool) error {
+// StreamScheduler returns the stream scheduler of the association.
+func (c *SCTPConn) StreamScheduler() (StreamScheduler, error) {
+	v, e := getAssocValue(c.sock(), sctpStreamScheduler, c.id)
+	if e != nil {
+		return SchedulerDefault, &net.OpError{
+			Op:     "getscheduler",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   c.RemoteAddr(),
+			Err:    e}
+	}
+	return schedulerFromValue(v), nil
+}
+
+// SetStreamScheduler set the stream scheduler of the association.
+func (c *SCTPConn) SetStreamScheduler(s StreamScheduler) error {
+	e := setAssocValue(c.sock(), sctpStreamScheduler, c.id, s.value())
+	if e != nil {
+		return &net.OpError{
+			Op:     "setscheduler",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   c.RemoteAddr(),
+			Err:    e}
+	}
+	return nil
+}
+
+type streamValue struct {
+	assocID assocT
+	stream  uint16
+	value   uint16
+}
+
+// SetStreamPriority set the scheduling parameter of the stream s.
+// It is priority for SchedulerPriority that lower value is higher priority,
+// or weight for SchedulerWFQ.
+func (c *SCTPConn) SetStreamPriority(s, v uint16) error {
+	attr := streamValue{
+		assocID: c.id,
+		stream:  s,
+		value:   v}
 	l := unsafe.Sizeof(attr)
 	p := unsafe.Pointer(&attr)
 
-	return setSockOpt(c.l.sock, sctpNodelay, p, l)
+	if e := setSockOpt(c.sock(), sctpStreamSchedulerValue, p, l); e != nil {
+		return &net.OpError{
+			Op:     "setstreampriority",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   c.RemoteAddr(),
+			Err:    e}
+	}
+	return nil
 }
 
-/*
+// StreamPriority returns the scheduling parameter of the stream s.
+func (c *SCTPConn) StreamPriority(s uint16) (uint16, error) {
+	attr := streamValue{
+		assocID: c.id,
+		stream:  s}
+	l := unsafe.Sizeof(attr)
+	p := unsafe.Pointer(&attr)
+
+	if e := getSockOpt(c.sock(), sctpStreamSchedulerValue, p, &l); e != nil {
+		return 0, &net.OpError{
+			Op:     "getstreampriority",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   c.RemoteAddr(),
+			Err:    e}
+	}
+	return attr.value, nil
+}
+
+// AssocState is the state of the association.
+type AssocState int
+
+// AssocState values
 const (
-	HB_ENABLE         = uint32(C.SPP_HB_ENABLE)
-	HB_DISABLE        = uint32(C.SPP_HB_DISABLE)
-	HB_DEMAND         = uint32(C.SPP_HB_DEMAND)
-	PMTUD_ENABLE      = uint32(C.SPP_PMTUD_ENABLE)
-	PMTUD_DISABLE     = uint32(C.SPP_PMTUD_DISABLE)
-	SACKDELAY_ENABLE  = uint32(C.SPP_SACKDELAY_ENABLE)
-	SACKDELAY_DISABLE = uint32(C.SPP_SACKDELAY_DISABLE)
-	HB_TIME_IS_ZERO   = uint32(C.SPP_HB_TIME_IS_ZERO)
+	AssocClosed           AssocState = sctpClosed
+	AssocCookieWait       AssocState = sctpCookieWait
+	AssocCookieEchoed     AssocState = sctpCookieEchoed
+	AssocEstablished      AssocState = sctpEstablished
+	AssocShutdownPending  AssocState = sctpShutdownPending
+	AssocShutdownSent     AssocState = sctpShutdownSent
+	AssocShutdownReceived AssocState = sctpShutdownReceived
+	AssocShutdownAckSent  AssocState = sctpShutdownAckSent
 )
 
-func (c *SCTPConn) SetPeerAddrParams(
-	hbinterval uint32, pathmaxrxt uint16, pathmtu, sackdelay, flags uint32) error {
-	attr := C.struct_sctp_paddrparams{}
-	l := C.socklen_t(unsafe.Sizeof(attr))
+func (s AssocState) String() string {
+	switch s {
+	case AssocClosed:
+		return "closed"
+	case AssocCookieWait:
+		return "cookie-wait"
+	case AssocCookieEchoed:
+		return "cookie-echoed"
+	case AssocEstablished:
+		return "established"
+	case AssocShutdownPending:
+		return "shutdown-pending"
+	case AssocShutdownSent:
+		return "shutdown-sent"
+	case AssocShutdownReceived:
+		return "shutdown-received"
+	case AssocShutdownAckSent:
+		return "shutdown-ack-sent"
+	}
+	return "unknown"
+}
 
-	attr.spp_assoc_id = c.id
-	attr.spp_hbinterval = C.__u32(hbinterval)
-	attr.spp_pathmaxrxt = C.__u16(pathmaxrxt)
-	//attr.spp_pathmtu = C.__u32(pathmtu)
-	//attr.spp_sackdelay = C.__u32(sackdelay)
-	//attr.spp_flags = C.__u32(flags)
+// AssocStatus is the status of the association.
+type AssocStatus struct {
+	State              AssocState
+	PeerRwnd           int
+	Unacked            int
+	Pending            int
+	InStream           int
+	OutStream          int
+	FragmentationPoint int
+	Primary            PathInfo
+}
 
+// Status returns current status of the association.
+func (c *SCTPConn) Status() (*AssocStatus, error) {
+	attr := status{assocID: c.id}
+	l := unsafe.Sizeof(attr)
 	p := unsafe.Pointer(&attr)
-	i, e := C.setsockopt(c.sock, C.SOL_SCTP, C.SCTP_PEER_ADDR_PARAMS, p, l)
-	if int(i) < 0 {
-		return e
+
+	if e := getSockOpt(c.sock(), sctpStatus, p, &l); e != nil {
+		return nil, &net.OpError{
+			Op:     "getstatus",
+			Net:    "sctp",
+			Source: c.LocalAddr(),
+			Addr:   c.RemoteAddr(),
+			Err:    e}
 	}
+	return &AssocStatus{
+		State:              AssocState(attr.state),
+		PeerRwnd:           int(attr.rwnd),
+		Unacked:            int(attr.unackdata),
+		Pending:            int(attr.penddata),
+		InStream:           int(attr.instrms),
+		OutStream:          int(attr.outstrms),
+		FragmentationPoint: int(attr.fragPoint),
+		Primary:            attr.primary.pathInfo()}, nil
+}
+
+// SetDeadline implements the Conn SetDeadline method.
+func (c *SCTPConn) SetDeadline(t time.Time) (e error) {
+	e = c.SetReadDeadline(t)
+	if e != nil {
+		return
+	}
+	e = c.SetWriteDeadline(t)
+	return
+}
+
+// SetReadDeadline implements the Conn SetReadDeadline method.
+// The deadline is applied to the pending read.
+func (c *SCTPConn) SetReadDeadline(t time.Time) error {
+	c.m.Lock()
+	c.rd = t
+	c.wc.Broadcast()
+	c.m.Unlock()
+	return nil
+}
+
+// SetWriteDeadline implements the Conn SetWriteDeadline method.
+// Write is blocked until the deadline if the send buffer is full,
+// and the message is abandoned by PR-SCTP when the deadline is exceeded.
+// The deadline is not applied to PR-SCTP if SetDefaultPRInfo sets
+// the default policy, the default policy is used instead.
+func (c *SCTPConn) SetWriteDeadline(t time.Time) error {
+	c.m.Lock()
+	c.wd = t
+	c.m.Unlock()
 	return nil
 }
-*/
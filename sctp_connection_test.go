@@ -1,6 +1,11 @@
 package extnet
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
 	"testing"
 	"time"
 )
@@ -365,3 +370,1089 @@ func TestAbortFromResp(t *testing.T) {
 		t.Errorf("close faied: %s", e)
 	}
 }
+
+func TestReadMsg(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	d0 := &SCTPDialer{
+		LocalAddr: a0,
+		OutStream: 16,
+		InStream:  16}
+	l0a, e := d0.Listen()
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+	l0 := l0a.(*SCTPListener)
+
+	d1 := &SCTPDialer{
+		LocalAddr: a1,
+		OutStream: 16,
+		InStream:  16}
+	c1a, e := d1.Dial("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+	c1 := c1a.(*SCTPConn)
+
+	c0, e := l0.AcceptSCTP()
+	if e != nil {
+		t.Errorf("accept faied: %s", e)
+	}
+
+	buf := make([]byte, 1024)
+	for i := 0; i < 16; i++ {
+		if _, e := c0.WriteToStream([]byte(testStr), uint16(i), uint32(i)); e != nil {
+			t.Errorf("write data failed: %s", e)
+		} else if n, info, e := c1.ReadMsg(buf); e != nil {
+			t.Errorf("read data failed: %s", e)
+		} else if n != len(testStr) {
+			t.Errorf("read data length is invalid: %d is not equal %d", n, len(testStr))
+		} else if info.Stream != uint16(i) || info.PPID != uint32(i) {
+			t.Errorf("invalid message info: stream=%d ppid=%d", info.Stream, info.PPID)
+		} else if info.AssocID != int(c1.id) {
+			t.Errorf("invalid assoc id %d is not equal %d", info.AssocID, c1.id)
+		}
+	}
+
+	large := make([]byte, RxBufferSize*3)
+	for i := range large {
+		large[i] = byte(i)
+	}
+	if _, e := c1.Write(large); e != nil {
+		t.Errorf("write data failed: %s", e)
+	}
+	if _, _, e := c0.ReadMsg(buf); e != io.ErrShortBuffer {
+		t.Errorf("short buffer must be detected: %s", e)
+	}
+	buf = make([]byte, len(large)+1)
+	if n, _, e := c0.ReadMsg(buf); e != nil {
+		t.Errorf("read data failed: %s", e)
+	} else if !bytes.Equal(buf[:n], large) {
+		t.Errorf("message is not reassembled: %d bytes", n)
+	}
+
+	e = c1.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+
+	e = l0.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
+
+func TestWriteMsg(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	l0, e := ListenSCTP("sctp", a0)
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+
+	c1, e := DialSCTP(a1, a0)
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+
+	c0, e := l0.AcceptSCTP()
+	if e != nil {
+		t.Errorf("accept faied: %s", e)
+	}
+
+	buf := make([]byte, 1024)
+	info := &SendInfo{
+		PPID:            46,
+		Unordered:       true,
+		Context:         10,
+		TimeToLive:      time.Second,
+		SackImmediately: true,
+		Addr:            a0.IP[0]}
+	if n, e := c1.WriteMsg([]byte(testStr), info); e != nil {
+		t.Errorf("write data failed: %s", e)
+	} else if n != len(testStr) {
+		t.Errorf("write data length is invalid: %d is not equal %d", n, len(testStr))
+	} else if n, ri, e := c0.ReadMsg(buf); e != nil {
+		t.Errorf("read data failed: %s", e)
+	} else if n != len(testStr) {
+		t.Errorf("read data length is invalid: %d is not equal %d", n, len(testStr))
+	} else if ri.PPID != info.PPID || !ri.Unordered() {
+		t.Errorf("invalid message info: ppid=%d unordered=%t", ri.PPID, ri.Unordered())
+	}
+
+	// address that is not a peer address must be rejected
+	info.Addr = net.IPv4(192, 0, 2, 1)
+	if _, e := c1.WriteMsg([]byte(testStr), info); e == nil {
+		t.Errorf("write to unknown address must fail")
+	}
+
+	e = c1.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+
+	e = l0.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
+
+func TestPaths(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	l0, e := ListenSCTP("sctp", a0)
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+
+	c1, e := DialSCTP(a1, a0)
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+
+	_, e = l0.Accept()
+	if e != nil {
+		t.Errorf("accept faied: %s", e)
+	}
+
+	p, e := c1.Paths()
+	if e != nil {
+		t.Errorf("get paths failed: %s", e)
+	} else if len(p) != len(a0.IP) {
+		t.Errorf("path count %d is not equal %d", len(p), len(a0.IP))
+	}
+	for _, i := range p {
+		t.Logf("%s: %s, cwnd=%d, srtt=%s, rto=%s, mtu=%d",
+			i.IP, i.State, i.Cwnd, i.SRTT, i.RTO, i.MTU)
+		if i.State == PathInactive {
+			t.Errorf("path %s is inactive", i.IP)
+		}
+	}
+
+	e = c1.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+
+	e = l0.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
+
+func TestPrimaryAddr(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	l0, e := ListenSCTP("sctp", a0)
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+
+	c1, e := DialSCTP(a1, a0)
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+
+	_, e = l0.Accept()
+	if e != nil {
+		t.Errorf("accept faied: %s", e)
+	}
+
+	ip := a0.IP[len(a0.IP)-1]
+	if e = c1.SetPrimaryAddr(ip); e != nil {
+		t.Errorf("set primary failed: %s", e)
+	} else if p, e := c1.PrimaryAddr(); e != nil {
+		t.Errorf("get primary failed: %s", e)
+	} else if !p.Equal(ip) {
+		t.Errorf("primary %s is not same as %s", p, ip)
+	}
+
+	if e = c1.SetPrimaryAddr(net.IPv4(203, 0, 113, 1)); e == nil {
+		t.Errorf("unknown address must be rejected")
+	}
+	if e = c1.RequestPeerPrimary(net.IPv4(203, 0, 113, 1)); e == nil {
+		t.Errorf("unknown address must be rejected")
+	}
+
+	e = c1.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+
+	e = l0.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
+
+func TestPeelOff(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	l0, e := ListenSCTP("sctp", a0)
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+
+	c1, e := DialSCTP(a1, a0)
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+
+	c0, e := l0.AcceptSCTP()
+	if e != nil {
+		t.Fatalf("accept faied: %s", e)
+	}
+
+	if e = c0.PeelOff(); e != nil {
+		t.Fatalf("peel off failed: %s", e)
+	}
+	if c0.sock() == l0.sock() {
+		t.Errorf("peeled off connection must have own socket")
+	}
+	if e = c0.PeelOff(); e == nil {
+		t.Errorf("peeled off connection must not be peeled off again")
+	}
+
+	buf := make([]byte, 1024)
+	if n, e := c0.Write([]byte(testStr)); e != nil {
+		t.Errorf("write data failed: %s", e)
+	} else if n != len(testStr) {
+		t.Errorf("write data length is invalid: %d is not equal %d", n, len(testStr))
+	} else if n, e = c1.Read(buf); e != nil {
+		t.Errorf("read data failed: %s", e)
+	} else if n != len(testStr) {
+		t.Errorf("read data length is invalid: %d is not equal %d", n, len(testStr))
+	}
+
+	if n, e := c1.Write([]byte(testStr)); e != nil {
+		t.Errorf("write data failed: %s", e)
+	} else if n != len(testStr) {
+		t.Errorf("write data length is invalid: %d is not equal %d", n, len(testStr))
+	} else if n, e = c0.Read(buf); e != nil {
+		t.Errorf("read data failed: %s", e)
+	} else if n != len(testStr) {
+		t.Errorf("read data length is invalid: %d is not equal %d", n, len(testStr))
+	}
+
+	e = c1.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+
+	e = l0.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	l0, e := ListenSCTP("sctp", a0)
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+
+	d := &SCTPDialer{LocalAddr: a1, OutStream: 3, InStream: 5}
+	c, e := d.Dial("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+	c1 := c.(*SCTPConn)
+
+	_, e = l0.Accept()
+	if e != nil {
+		t.Errorf("accept faied: %s", e)
+	}
+
+	s, e := c1.Status()
+	if e != nil {
+		t.Errorf("get status failed: %s", e)
+	} else {
+		t.Logf("%s: rwnd=%d, unacked=%d, pending=%d, in=%d, out=%d, frag=%d, primary=%s",
+			s.State, s.PeerRwnd, s.Unacked, s.Pending,
+			s.InStream, s.OutStream, s.FragmentationPoint, s.Primary.IP)
+		if s.State != AssocEstablished {
+			t.Errorf("association state %s is not established", s.State)
+		}
+		if s.OutStream != 3 {
+			t.Errorf("out stream %d is not equal 3", s.OutStream)
+		}
+		if a0.index(s.Primary.IP) < 0 {
+			t.Errorf("primary address %s is not a peer address", s.Primary.IP)
+		}
+	}
+
+	e = c1.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+
+	e = l0.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
+
+func TestStreamReset(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	d0 := &SCTPDialer{
+		LocalAddr:   a0,
+		StreamReset: true}
+	l0a, e := d0.Listen()
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+	l0 := l0a.(*SCTPListener)
+
+	d1 := &SCTPDialer{
+		LocalAddr:   a1,
+		OutStream:   4,
+		InStream:    4,
+		StreamReset: true}
+	c1a, e := d1.Dial("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+	c1 := c1a.(*SCTPConn)
+
+	_, e = l0.AcceptSCTP()
+	if e != nil {
+		t.Errorf("accept faied: %s", e)
+	}
+
+	s, e := c1.Status()
+	if e != nil {
+		t.Fatalf("get status failed: %s", e)
+	}
+	out := s.OutStream
+
+	if e = c1.AddStreams(0, 2); e != nil {
+		t.Errorf("add streams failed: %s", e)
+	}
+	for i := 0; i < 10 && s.OutStream == out; i++ {
+		time.Sleep(time.Millisecond * 100)
+		if s, e = c1.Status(); e != nil {
+			t.Fatalf("get status failed: %s", e)
+		}
+	}
+	if s.OutStream != out+2 {
+		t.Errorf("out stream %d is not equal %d", s.OutStream, out+2)
+	}
+
+	if e = c1.ResetStreams(nil, []uint16{0, 1}); e != nil {
+		t.Errorf("reset streams failed: %s", e)
+	}
+	if e = c1.ResetStreams([]uint16{0}, []uint16{1}); e == nil {
+		t.Errorf("reset different streams of both direction must fail")
+	}
+	time.Sleep(time.Millisecond * 100)
+	if e = c1.ResetStreams([]uint16{0, 1}, []uint16{1, 0}); e != nil {
+		t.Errorf("reset streams of both direction failed: %s", e)
+	}
+
+	e = c1.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+
+	e = l0.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
+
+func TestPartialReliability(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	d0 := &SCTPDialer{
+		LocalAddr:          a0,
+		PartialReliability: true}
+	l0a, e := d0.Listen()
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+	l0 := l0a.(*SCTPListener)
+
+	d1 := &SCTPDialer{
+		LocalAddr:          a1,
+		PartialReliability: true}
+	c1a, e := d1.Dial("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+	c1 := c1a.(*SCTPConn)
+
+	c0, e := l0.AcceptSCTP()
+	if e != nil {
+		t.Errorf("accept faied: %s", e)
+	}
+
+	if e = c1.SetDefaultPRInfo(PRInfo{Policy: PRRtx, Value: 3}); e != nil {
+		t.Errorf("set default prinfo failed: %s", e)
+	} else if p, e := c1.DefaultPRInfo(); e != nil {
+		t.Errorf("get default prinfo failed: %s", e)
+	} else if p.Policy != PRRtx || p.Value != 3 {
+		t.Errorf("default prinfo %s:%d is not equal rtx:3", p.Policy, p.Value)
+	}
+
+	buf := make([]byte, 1024)
+	info := &SendInfo{PR: &PRInfo{Policy: PRTTL, Value: 1000}}
+	if n, e := c1.WriteMsg([]byte(testStr), info); e != nil {
+		t.Errorf("write data failed: %s", e)
+	} else if n != len(testStr) {
+		t.Errorf("write data length is invalid: %d is not equal %d", n, len(testStr))
+	} else if n, e = c0.Read(buf); e != nil {
+		t.Errorf("read data failed: %s", e)
+	} else if n != len(testStr) {
+		t.Errorf("read data length is invalid: %d is not equal %d", n, len(testStr))
+	}
+
+	if s, e := c1.PRStatus(); e != nil {
+		t.Errorf("get prstatus failed: %s", e)
+	} else if s.AbandonedSent != 0 || s.AbandonedUnsent != 0 {
+		t.Errorf("abandoned message %d/%d must be 0",
+			s.AbandonedSent, s.AbandonedUnsent)
+	}
+	if _, e := c1.StreamPRStatus(0); e != nil {
+		t.Errorf("get stream prstatus failed: %s", e)
+	}
+
+	e = c1.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+
+	e = l0.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
+
+func TestStreamScheduler(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	l0, e := ListenSCTP("sctp", a0)
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+
+	d1 := &SCTPDialer{
+		LocalAddr: a1,
+		Scheduler: SchedulerPriority}
+	c1a, e := d1.Dial("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+	c1 := c1a.(*SCTPConn)
+
+	_, e = l0.AcceptSCTP()
+	if e != nil {
+		t.Errorf("accept faied: %s", e)
+	}
+
+	if s, e := c1.StreamScheduler(); e != nil {
+		t.Errorf("get scheduler failed: %s", e)
+	} else if s != SchedulerPriority {
+		t.Errorf("scheduler %s is not equal %s", s, SchedulerPriority)
+	}
+
+	if e = c1.SetStreamPriority(1, 5); e != nil {
+		t.Errorf("set stream priority failed: %s", e)
+	} else if v, e := c1.StreamPriority(1); e != nil {
+		t.Errorf("get stream priority failed: %s", e)
+	} else if v != 5 {
+		t.Errorf("stream priority %d is not equal 5", v)
+	}
+
+	if e = c1.SetStreamScheduler(SchedulerRoundRobin); e != nil {
+		t.Errorf("set scheduler failed: %s", e)
+	} else if s, e := c1.StreamScheduler(); e != nil {
+		t.Errorf("get scheduler failed: %s", e)
+	} else if s != SchedulerRoundRobin {
+		t.Errorf("scheduler %s is not equal %s", s, SchedulerRoundRobin)
+	}
+
+	e = c1.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+
+	e = l0.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
+
+func TestInterleave(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	d0 := &SCTPDialer{
+		LocalAddr:  a0,
+		Interleave: true}
+	l0a, e := d0.Listen()
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+	l0 := l0a.(*SCTPListener)
+
+	d1 := &SCTPDialer{
+		LocalAddr:  a1,
+		Interleave: true}
+	c1a, e := d1.Dial("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+	c1 := c1a.(*SCTPConn)
+
+	c0, e := l0.AcceptSCTP()
+	if e != nil {
+		t.Errorf("accept faied: %s", e)
+	}
+
+	large := bytes.Repeat([]byte(testStr), RxBufferSize)
+	go func() {
+		if _, e := c1.WriteToStream(large, 1, 0); e != nil {
+			t.Errorf("write data failed: %s", e)
+		}
+	}()
+	if _, e := c1.WriteToStream([]byte(testStr), 0, 0); e != nil {
+		t.Errorf("write data failed: %s", e)
+	}
+
+	buf := make([]byte, len(large))
+	for i := 0; i < 2; i++ {
+		n, info, e := c0.ReadMsg(buf)
+		if e != nil {
+			t.Errorf("read data failed: %s", e)
+			break
+		}
+		switch info.Stream {
+		case 0:
+			if !bytes.Equal(buf[:n], []byte(testStr)) {
+				t.Errorf("small message is broken")
+			}
+		case 1:
+			if !bytes.Equal(buf[:n], large) {
+				t.Errorf("large message is broken")
+			}
+		default:
+			t.Errorf("unknown stream %d", info.Stream)
+		}
+	}
+
+	e = c1.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+
+	e = l0.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
+
+func TestPeerAddrParams(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	l0, e := ListenSCTP("sctp", a0)
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+
+	d1 := &SCTPDialer{
+		LocalAddr: a1,
+		PeerAddrParams: &PeerAddrParams{
+			HeartbeatInterval: time.Second * 5,
+			PathMaxRetrans:    3,
+			Flags:             HbEnable}}
+	c1a, e := d1.Dial("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+	c1 := c1a.(*SCTPConn)
+
+	_, e = l0.AcceptSCTP()
+	if e != nil {
+		t.Errorf("accept faied: %s", e)
+	}
+
+	if p, e := c1.PeerAddrParams(nil); e != nil {
+		t.Errorf("get peer address params failed: %s", e)
+	} else if p.HeartbeatInterval != time.Second*5 || p.PathMaxRetrans != 3 {
+		t.Errorf("peer address params hb=%s, rxt=%d is not equal hb=5s, rxt=3",
+			p.HeartbeatInterval, p.PathMaxRetrans)
+	}
+
+	if e = c1.SetPeerAddrParams(PeerAddrParams{
+		Addr:              a0.IP[0],
+		HeartbeatInterval: time.Second,
+		Flags:             HbEnable | HbDemand}); e != nil {
+		t.Errorf("set peer address params failed: %s", e)
+	} else if p, e := c1.PeerAddrParams(a0.IP[0]); e != nil {
+		t.Errorf("get peer address params failed: %s", e)
+	} else if p.HeartbeatInterval != time.Second {
+		t.Errorf("heartbeat interval %s is not equal 1s", p.HeartbeatInterval)
+	} else if p.Flags&HbEnable != HbEnable {
+		t.Errorf("heartbeat is not enabled")
+	}
+
+	e = c1.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+
+	e = l0.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	l0, e := ListenSCTP("sctp", a0)
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+
+	c1, e := DialSCTP(a1, a0)
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+
+	c0, e := l0.AcceptSCTP()
+	if e != nil {
+		t.Fatalf("accept faied: %s", e)
+	}
+
+	// deadline is updated while reading
+	go func() {
+		time.Sleep(time.Millisecond * 100)
+		c0.SetReadDeadline(time.Now())
+	}()
+	if _, e = c0.Read(make([]byte, 1024)); e == nil {
+		t.Errorf("read must fail after the deadline")
+	} else if ne, ok := e.(net.Error); !ok || !ne.Timeout() {
+		t.Errorf("read error %s is not timeout", e)
+	}
+	c0.SetReadDeadline(time.Time{})
+
+	rc, e := c0.SyscallConn()
+	if e != nil {
+		t.Fatalf("get syscall conn faied: %s", e)
+	}
+	fd := -1
+	if e = rc.Control(func(s uintptr) { fd = int(s) }); e != nil {
+		t.Errorf("control faied: %s", e)
+	} else if fd != c0.sock() {
+		t.Errorf("socket %d is not same as %d", fd, c0.sock())
+	}
+
+	if e = c1.Close(); e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+	if e = l0.Close(); e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
+
+func TestHalfClose(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	l0, e := ListenSCTP("sctp", a0)
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+
+	c1, e := DialSCTP(a1, a0)
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+
+	c0, e := l0.AcceptSCTP()
+	if e != nil {
+		t.Fatalf("accept faied: %s", e)
+	}
+
+	// request is read after closing write
+	if _, e = c0.Write([]byte("request")); e != nil {
+		t.Errorf("write faied: %s", e)
+	}
+	b := make([]byte, 1024)
+	n, e := c1.Read(b)
+	if e != nil {
+		t.Errorf("read faied: %s", e)
+	}
+	if _, e = c1.Write(b[:n]); e != nil {
+		t.Errorf("write faied: %s", e)
+	}
+	time.Sleep(time.Millisecond * 100)
+
+	if e = c0.CloseWrite(); e != nil {
+		t.Errorf("close write faied: %s", e)
+	}
+	if n, e = c0.Read(b); e != nil {
+		t.Errorf("read faied: %s", e)
+	} else if string(b[:n]) != "request" {
+		t.Errorf("output %s is not same as request", b[:n])
+	}
+	if _, e = c0.Read(b); e != io.EOF {
+		t.Errorf("read must return EOF after shutdown: %v", e)
+	}
+	if _, e = c1.Read(b); e != io.EOF {
+		t.Errorf("read must return EOF after shutdown: %v", e)
+	}
+
+	c1, e = DialSCTP(a1, a0)
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+	c0, e = l0.AcceptSCTP()
+	if e != nil {
+		t.Fatalf("accept faied: %s", e)
+	}
+
+	// data is discarded after closing read
+	if e = c0.CloseRead(); e != nil {
+		t.Errorf("close read faied: %s", e)
+	}
+	if _, e = c1.Write([]byte("discarded")); e != nil {
+		t.Errorf("write faied: %s", e)
+	}
+	if _, e = c0.Read(b); e != io.EOF {
+		t.Errorf("read must return EOF after close read: %v", e)
+	}
+
+	if e = c1.Close(); e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+	if e = l0.Close(); e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
+
+func TestCloseTimeout(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	l0, e := ListenSCTP("sctp", a0)
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+
+	// unread data is reported by close
+	c1, e := DialSCTP(a1, a0)
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+	c0, e := l0.AcceptSCTP()
+	if e != nil {
+		t.Fatalf("accept faied: %s", e)
+	}
+	if _, e = c0.Write([]byte("unread")); e != nil {
+		t.Errorf("write faied: %s", e)
+	}
+	time.Sleep(time.Millisecond * 100)
+	if e = c1.Close(); e == nil {
+		t.Errorf("close must fail with unread data")
+	}
+
+	// association is aborted by close
+	d := &SCTPDialer{LocalAddr: a1, CloseTimeout: -1}
+	c, e := d.Dial("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+	c0, e = l0.AcceptSCTP()
+	if e != nil {
+		t.Fatalf("accept faied: %s", e)
+	}
+	if e = c.Close(); e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+	if _, e = c0.Read(make([]byte, 1024)); !errors.Is(e, ErrAborted) {
+		t.Errorf("read must return ErrAborted after abort: %v", e)
+	}
+
+	// shutdown is not completed before the context is done
+	c1, e = DialSCTP(a1, a0)
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+	if _, e = l0.AcceptSCTP(); e != nil {
+		t.Fatalf("accept faied: %s", e)
+	}
+	// blocked reader does not block close
+	go c1.Read(make([]byte, 1024))
+	time.Sleep(time.Millisecond * 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if e = c1.CloseContext(ctx); e == nil {
+		t.Errorf("close must fail with canceled context")
+	}
+
+	if e = l0.Close(); e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
+
+func TestAbortReason(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	l0, e := ListenSCTP("sctp", a0)
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+
+	c1, e := DialSCTP(a1, a0)
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+
+	c0, e := l0.AcceptSCTP()
+	if e != nil {
+		t.Fatalf("accept faied: %s", e)
+	}
+
+	if e = c1.Abort("test reason"); e != nil {
+		t.Errorf("abort faied: %s", e)
+	}
+
+	_, e = c0.Read(make([]byte, 1024))
+	var ae *AssocError
+	if !errors.As(e, &ae) {
+		t.Fatalf("read error %v is not AssocError", e)
+	}
+	if ae.Err != ErrAborted {
+		t.Errorf("error %s is not ErrAborted", ae.Err)
+	}
+	if ae.Cause != CauseUserInitiatedAbort {
+		t.Errorf("cause %d is not user initiated abort", ae.Cause)
+	}
+	if ae.Reason != "test reason" {
+		t.Errorf("reason %s is not same as test reason", ae.Reason)
+	}
+
+	if _, e = c0.Write([]byte("hello")); !errors.Is(e, ErrAborted) {
+		t.Errorf("write must return ErrAborted after abort: %v", e)
+	}
+
+	if e = l0.Close(); e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
+
+func TestCloseOneToOne(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	d0 := &SCTPDialer{LocalAddr: a0, OneToOne: true}
+	l, e := d0.Listen()
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+	l0 := l.(*SCTPListener)
+	d1 := &SCTPDialer{LocalAddr: a1, OneToOne: true}
+	buf := make([]byte, 1024)
+
+	// peer sees shutdown
+	c, e := d1.Dial("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+	c0, e := l0.AcceptSCTP()
+	if e != nil {
+		t.Fatalf("accept faied: %s", e)
+	}
+	if e = c.Close(); e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+	if _, e = c0.Read(buf); e != io.EOF {
+		t.Errorf("read must return io.EOF after shutdown: %v", e)
+	}
+
+	// peer sees abort
+	c, e = d1.Dial("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+	c0, e = l0.AcceptSCTP()
+	if e != nil {
+		t.Fatalf("accept faied: %s", e)
+	}
+	if e = c.(*SCTPConn).Abort("test reason"); e != nil {
+		t.Errorf("abort faied: %s", e)
+	}
+	if _, e = c0.Read(buf); !errors.Is(e, ErrAborted) {
+		t.Errorf("read must return ErrAborted after abort: %v", e)
+	}
+
+	if e = l0.Close(); e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	c := &SCTPConn{part: map[partKey]*message{
+		{stream: 1}:                  {b: []byte("ordered")},
+		{stream: 1, unordered: true}: {b: []byte("unordered")}}}
+
+	// only the aborted message is dropped with interleaving
+	c.discard(1, true)
+	if _, ok := c.part[partKey{stream: 1, unordered: true}]; ok {
+		t.Errorf("aborted unordered message is not dropped")
+	}
+	if _, ok := c.part[partKey{stream: 1}]; !ok {
+		t.Errorf("ordered message must not be dropped")
+	}
+
+	// stream is not notified without interleaving
+	c.discard(0, false)
+	if len(c.part) != 0 {
+		t.Errorf("partially delivered message is not dropped")
+	}
+}
@@ -0,0 +1,258 @@
+package extnet
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Event is the notification or trace event of the listener
+// and the association.
+type Event interface {
+	error
+	// AssocID returns ID of the association, or 0 if the event
+	// is not related to any association.
+	AssocID() int
+	// Timestamp returns the time that the event is occurred.
+	Timestamp() time.Time
+}
+
+// Notification is a set of SCTP notifications that the socket subscribes.
+type Notification uint32
+
+// Notifications of SCTP socket
+const (
+	// NotifyAssocChange is always subscribed for handling associations.
+	NotifyAssocChange Notification = 1 << iota
+	NotifyPeerAddrChange
+	NotifySendFailed
+	NotifyRemoteError
+	// NotifyShutdown makes Read return io.EOF when the peer starts
+	// shutdown, not when the shutdown is completed.
+	NotifyShutdown
+	NotifyPartialDelivery
+	NotifyAdaptation
+	NotifyAuthentication
+	NotifySenderDry
+	NotifyStreamReset
+	NotifyAssocReset
+	NotifyStreamChange
+
+	NotifyAll Notification = 1<<iota - 1
+)
+
+// notifyTypes is notification types of each Notification,
+// legacy is true if the type is available by SCTP_EVENTS.
+var notifyTypes = []struct {
+	n      Notification
+	t      uint16
+	legacy bool
+}{
+	{NotifyAssocChange, sctpAssocChange, true},
+	{NotifyPeerAddrChange, sctpPeerAddrChange, true},
+	{NotifySendFailed, sctpSendFailed, true},
+	{NotifyRemoteError, sctpRemoteError, true},
+	{NotifyShutdown, sctpShutdownEvent, true},
+	{NotifyPartialDelivery, sctpPartialDeliveryEvent, true},
+	{NotifyAdaptation, sctpAdaptationIndication, true},
+	{NotifyAuthentication, sctpAuthenticationEvent, true},
+	{NotifySenderDry, sctpSenderDryEvent, true},
+	{NotifyStreamReset, sctpStreamResetEvent, false},
+	{NotifyAssocReset, sctpAssocResetEvent, false},
+	{NotifyStreamChange, sctpStreamChangeEvent, false}}
+
+// setNotify subscribes notifications n by SCTP_EVENT, or by
+// the legacy SCTP_EVENTS if SCTP_EVENT is not supported.
+func setNotify(fd int, n Notification) error {
+	if n == 0 {
+		n = defaultNotifications
+	}
+	n |= NotifyAssocChange
+
+	// data io event is only in the legacy option, it is required
+	// for sndrcvinfo of received data
+	if e := setEvents(fd, 0); e != nil {
+		return e
+	}
+	if e := setEvent(fd, sctpAssocChange, true); e != nil {
+		return setEvents(fd, n)
+	}
+	for _, t := range notifyTypes {
+		if n&t.n == 0 {
+			continue
+		}
+		// error is ignored for the type that is not supported
+		if e := setEvent(fd, t.t, true); e != nil && t.legacy {
+			return e
+		}
+	}
+	return nil
+}
+
+// EventTime is the time of the event, it is embedded in each event.
+type EventTime struct {
+	Time time.Time
+}
+
+// Timestamp returns the time that the event is occurred.
+func (t *EventTime) Timestamp() time.Time {
+	return t.Time
+}
+
+func (t *EventTime) stamp(now time.Time) {
+	t.Time = now
+}
+
+// subscriber queues events and calls the handler in own goroutine,
+// then slow handler does not block receiving.
+type subscriber struct {
+	h     func(Event)
+	types []reflect.Type
+	id    assocT
+
+	m    sync.Mutex
+	c    sync.Cond
+	q    []Event
+	done bool
+}
+
+func newSubscriber(h func(Event), id assocT, types []Event) *subscriber {
+	s := &subscriber{h: h, id: id}
+	s.c.L = &s.m
+	for _, t := range types {
+		s.types = append(s.types, reflect.TypeOf(t))
+	}
+	go s.run()
+	return s
+}
+
+func (s *subscriber) match(e Event) bool {
+	if s.id != 0 && assocT(e.AssocID()) != s.id {
+		return false
+	}
+	if len(s.types) == 0 {
+		return true
+	}
+	t := reflect.TypeOf(e)
+	for _, f := range s.types {
+		if f == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *subscriber) push(e Event) {
+	s.m.Lock()
+	if !s.done {
+		s.q = append(s.q, e)
+		s.c.Signal()
+	}
+	s.m.Unlock()
+}
+
+// stop stops the subscriber, queued events are dropped
+// if flush is false.
+func (s *subscriber) stop(flush bool) {
+	s.m.Lock()
+	s.done = true
+	if !flush {
+		s.q = nil
+	}
+	s.c.Signal()
+	s.m.Unlock()
+}
+
+func (s *subscriber) run() {
+	for {
+		s.m.Lock()
+		for len(s.q) == 0 && !s.done {
+			s.c.Wait()
+		}
+		if len(s.q) == 0 {
+			s.m.Unlock()
+			return
+		}
+		e := s.q[0]
+		s.q[0] = nil
+		s.q = s.q[1:]
+		s.m.Unlock()
+
+		s.h(e)
+	}
+}
+
+// Subscribe registers the handler h that is called with events
+// of the listener and its associations.
+// Only events of the same types as types are passed to h
+// if types is not empty, such as Subscribe(h, &SctpAssocUp{}).
+// h is called in order of the events on own goroutine, and
+// it is stopped by returned function or by closing the listener.
+// h is never called if the listener is already closed.
+func (l *SCTPListener) Subscribe(h func(Event), types ...Event) (unsubscribe func()) {
+	return l.subscribe(h, 0, types, l.isClosed)
+}
+
+// Subscribe registers the handler h that is called with events
+// of the association. See Subscribe of SCTPListener for types.
+// It is stopped by returned function or by closing the association.
+// h is never called if the association is already closed.
+func (c *SCTPConn) Subscribe(h func(Event), types ...Event) (unsubscribe func()) {
+	return c.l.subscribe(h, c.id, types, c.closed)
+}
+
+// subscribe registers the subscriber unless closed returns true.
+// closed is checked with l.sm locked, then the subscriber is stopped
+// by the following unsubscribe of the closing.
+func (l *SCTPListener) subscribe(h func(Event), id assocT, types []Event, closed func() bool) func() {
+	l.sm.Lock()
+	if closed() {
+		l.sm.Unlock()
+		return func() {}
+	}
+	s := newSubscriber(h, id, types)
+	if l.subs == nil {
+		l.subs = make(map[*subscriber]bool)
+	}
+	l.subs[s] = true
+	l.sm.Unlock()
+
+	return func() {
+		l.sm.Lock()
+		delete(l.subs, s)
+		l.sm.Unlock()
+		s.stop(false)
+	}
+}
+
+// unsubscribe stops subscribers of the association id,
+// or subscribers of the listener if id is 0,
+// after queued events are passed.
+func (l *SCTPListener) unsubscribe(id assocT) {
+	l.sm.Lock()
+	defer l.sm.Unlock()
+	for s := range l.subs {
+		if s.id == id {
+			delete(l.subs, s)
+			s.stop(true)
+		}
+	}
+}
+
+// notify passes the event to Notificator and subscribers.
+func (l *SCTPListener) notify(e Event) {
+	if t, ok := e.(interface{ stamp(time.Time) }); ok {
+		t.stamp(time.Now())
+	}
+	if Notificator != nil {
+		Notificator(e)
+	}
+
+	l.sm.Lock()
+	defer l.sm.Unlock()
+	for s := range l.subs {
+		if s.match(e) {
+			s.push(e)
+		}
+	}
+}
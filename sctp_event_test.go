@@ -0,0 +1,202 @@
+package extnet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscribe(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	l0, e := ListenSCTP("sctp", a0)
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+	up := make(chan Event, 10)
+	l0.Subscribe(func(e Event) { up <- e }, &SctpAssocUp{})
+
+	c1, e := DialSCTP(a1, a0)
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+	c0, e := l0.AcceptSCTP()
+	if e != nil {
+		t.Fatalf("accept faied: %s", e)
+	}
+
+	select {
+	case ev := <-up:
+		if _, ok := ev.(*SctpAssocUp); !ok {
+			t.Errorf("event %s is not association up", ev)
+		}
+		if ev.AssocID() != int(c0.id) {
+			t.Errorf("association id %d is not same as %d", ev.AssocID(), c0.id)
+		}
+		if ev.Timestamp().IsZero() {
+			t.Errorf("timestamp of the event is not set")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("association up is not notified")
+	}
+
+	// slow handler does not block reading
+	data := make(chan Event, 10)
+	unsubscribe := c0.Subscribe(func(e Event) {
+		time.Sleep(time.Millisecond * 100)
+		data <- e
+	}, &SctpRecieveData{})
+
+	if _, e = c1.Write([]byte("hello")); e != nil {
+		t.Errorf("write faied: %s", e)
+	}
+	b := make([]byte, 1024)
+	c0.SetReadDeadline(time.Now().Add(time.Millisecond * 50))
+	if _, e = c0.Read(b); e != nil {
+		t.Errorf("read faied: %s", e)
+	}
+	select {
+	case ev := <-data:
+		if ev.AssocID() != int(c0.id) {
+			t.Errorf("association id %d is not same as %d", ev.AssocID(), c0.id)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("received data is not notified")
+	}
+	unsubscribe()
+
+	if e = c1.Close(); e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+
+	// subscription after close is not registered
+	unsubscribe = c1.Subscribe(func(e Event) {})
+	c1.l.sm.Lock()
+	if n := len(c1.l.subs); n != 0 {
+		t.Errorf("%d subscribers remain after close", n)
+	}
+	c1.l.sm.Unlock()
+	unsubscribe()
+	if e = l0.Close(); e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
+
+func TestSubscribePeelOff(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	l0, e := ListenSCTP("sctp", a0)
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+	c1, e := DialSCTP(a1, a0)
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+	c0, e := l0.AcceptSCTP()
+	if e != nil {
+		t.Fatalf("accept faied: %s", e)
+	}
+
+	// subscription of the dialed connection survives peel off
+	data := make(chan Event, 10)
+	c1.Subscribe(func(e Event) { data <- e }, &SctpRecieveData{})
+	if e = c1.PeelOff(); e != nil {
+		t.Fatalf("peel off failed: %s", e)
+	}
+	time.Sleep(time.Millisecond * 100)
+
+	if _, e = c0.Write([]byte("hello")); e != nil {
+		t.Errorf("write faied: %s", e)
+	}
+	if _, e = c1.Read(make([]byte, 1024)); e != nil {
+		t.Errorf("read faied: %s", e)
+	}
+	select {
+	case ev := <-data:
+		if ev.AssocID() != int(c1.id) {
+			t.Errorf("association id %d is not same as %d", ev.AssocID(), c1.id)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("received data is not notified after peel off")
+	}
+
+	if e = c1.Close(); e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+	if e = l0.Close(); e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	d := &SCTPDialer{LocalAddr: a0, Notifications: NotifyAssocChange}
+	l, e := d.Listen()
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+	l0 := l.(*SCTPListener)
+	shutdown := make(chan Event, 10)
+	l0.Subscribe(func(e Event) { shutdown <- e }, &SctpShutdown{})
+
+	c1, e := DialSCTP(a1, a0)
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+	c0, e := l0.AcceptSCTP()
+	if e != nil {
+		t.Fatalf("accept faied: %s", e)
+	}
+
+	if _, e = c1.Write([]byte("hello")); e != nil {
+		t.Errorf("write faied: %s", e)
+	}
+	b := make([]byte, 1024)
+	if n, e := c0.Read(b); e != nil {
+		t.Errorf("read faied: %s", e)
+	} else if string(b[:n]) != "hello" {
+		t.Errorf("output %s is not same as hello", b[:n])
+	}
+
+	if e = c1.Close(); e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+	time.Sleep(time.Millisecond * 100)
+	select {
+	case ev := <-shutdown:
+		t.Errorf("unsubscribed event %s is notified", ev)
+	default:
+	}
+
+	if e = l0.Close(); e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
@@ -9,7 +9,11 @@ package extnet
 import "C"
 
 import (
+	"errors"
+	"os"
+	"sync"
 	"syscall"
+	"time"
 	"unsafe"
 )
 
@@ -22,12 +26,13 @@ const (
 	// SCTP_SENDALL = C.SCTP_SENDALL
 	// SCTP_EOR = C.SCTP_EOR
 
-	//SCTP_SACK_IMMEDIATELY = C.SCTP_SACK_IMMEDIATELY
+	sctpSackImmediately = C.SCTP_SACK_IMMEDIATELY
 
 	// SOL_SCTP    = C.SOL_SCTP
 	// SCTP_EVENTS = C.SCTP_EVENTS
 
 	msgNotification          = C.MSG_NOTIFICATION
+	msgEoR                   = C.MSG_EOR
 	sctpAssocChange          = C.SCTP_ASSOC_CHANGE
 	sctpPeerAddrChange       = C.SCTP_PEER_ADDR_CHANGE
 	sctpRemoteError          = C.SCTP_REMOTE_ERROR
@@ -36,6 +41,10 @@ const (
 	sctpAdaptationIndication = C.SCTP_ADAPTATION_INDICATION
 	sctpPartialDeliveryEvent = C.SCTP_PARTIAL_DELIVERY_EVENT
 	sctpSenderDryEvent       = C.SCTP_SENDER_DRY_EVENT
+	sctpAuthenticationEvent  = C.SCTP_AUTHENTICATION_EVENT
+	sctpStreamResetEvent     = C.SCTP_STREAM_RESET_EVENT
+	sctpAssocResetEvent      = C.SCTP_ASSOC_RESET_EVENT
+	sctpStreamChangeEvent    = C.SCTP_STREAM_CHANGE_EVENT
 
 	sctpCommUp       = C.SCTP_COMM_UP
 	sctpCommLost     = C.SCTP_COMM_LOST
@@ -50,15 +59,184 @@ const (
 	sctpAddrMadePrim    = C.SCTP_ADDR_MADE_PRIM
 	sctpAddrConfirmed   = C.SCTP_ADDR_CONFIRMED
 
+	sctpBindxAddAddr = C.SCTP_BINDX_ADD_ADDR
+	sctpBindxRemAddr = C.SCTP_BINDX_REM_ADDR
+
 	sctpInitMsg   = C.SCTP_INITMSG
 	sctpRtoInfo   = C.SCTP_RTOINFO
 	sctpAssocInfo = C.SCTP_ASSOCINFO
 	sctpNodelay   = C.SCTP_NODELAY
+
+	sctpMaxSeg               = C.SCTP_MAXSEG
+	sctpMaxBurst             = C.SCTP_MAX_BURST
+	sctpDelayedSack          = C.SCTP_DELAYED_SACK
+	sctpContext              = C.SCTP_CONTEXT
+	sctpDefaultSndInfo       = C.SCTP_DEFAULT_SNDINFO
+	sctpPartialDeliveryPoint = C.SCTP_PARTIAL_DELIVERY_POINT
+	sctpDisableFragments     = C.SCTP_DISABLE_FRAGMENTS
+	sctpAutoclose            = C.SCTP_AUTOCLOSE
+
+	sockRcvBuf = syscall.SO_RCVBUF
+	sockSndBuf = syscall.SO_SNDBUF
+
+	sctpGetPeerAddrInfo    = C.SCTP_GET_PEER_ADDR_INFO
+	sctpPrimaryAddr        = C.SCTP_PRIMARY_ADDR
+	sctpSetPeerPrimaryAddr = C.SCTP_SET_PEER_PRIMARY_ADDR
+
+	sctpInactive    = C.SCTP_INACTIVE
+	sctpPf          = C.SCTP_PF
+	sctpActive      = C.SCTP_ACTIVE
+	sctpUnconfirmed = C.SCTP_UNCONFIRMED
+
+	sctpStatus = C.SCTP_STATUS
+
+	sctpClosed           = C.SCTP_CLOSED
+	sctpCookieWait       = C.SCTP_COOKIE_WAIT
+	sctpCookieEchoed     = C.SCTP_COOKIE_ECHOED
+	sctpEstablished      = C.SCTP_ESTABLISHED
+	sctpShutdownPending  = C.SCTP_SHUTDOWN_PENDING
+	sctpShutdownSent     = C.SCTP_SHUTDOWN_SENT
+	sctpShutdownReceived = C.SCTP_SHUTDOWN_RECEIVED
+	sctpShutdownAckSent  = C.SCTP_SHUTDOWN_ACK_SENT
+
+	sctpEvent              = C.SCTP_EVENT
+	sctpReconfigSupported  = C.SCTP_RECONFIG_SUPPORTED
+	sctpEnableStreamReset  = C.SCTP_ENABLE_STREAM_RESET
+	sctpResetStreams       = C.SCTP_RESET_STREAMS
+	sctpResetAssoc         = C.SCTP_RESET_ASSOC
+	sctpAddStreams         = C.SCTP_ADD_STREAMS
+	sctpEnableResetStream  = C.SCTP_ENABLE_RESET_STREAM_REQ
+	sctpEnableResetAssoc   = C.SCTP_ENABLE_RESET_ASSOC_REQ
+	sctpEnableChangeAssoc  = C.SCTP_ENABLE_CHANGE_ASSOC_REQ
+	sctpStreamResetIn      = C.SCTP_STREAM_RESET_INCOMING
+	sctpStreamResetOut     = C.SCTP_STREAM_RESET_OUTGOING
+	sctpStreamResetInSsn   = C.SCTP_STREAM_RESET_INCOMING_SSN
+	sctpStreamResetOutSsn  = C.SCTP_STREAM_RESET_OUTGOING_SSN
+	sctpStreamResetDenied  = C.SCTP_STREAM_RESET_DENIED
+	sctpStreamResetFailed  = C.SCTP_STREAM_RESET_FAILED
+	sctpAssocResetDenied   = C.SCTP_ASSOC_RESET_DENIED
+	sctpAssocResetFailed   = C.SCTP_ASSOC_RESET_FAILED
+	sctpStreamChangeDenied = C.SCTP_STREAM_CHANGE_DENIED
+	sctpStreamChangeFailed = C.SCTP_STREAM_CHANGE_FAILED
+
+	sctpPrSupported    = C.SCTP_PR_SUPPORTED
+	sctpDefaultPrinfo  = C.SCTP_DEFAULT_PRINFO
+	sctpPrAssocStatus  = C.SCTP_PR_ASSOC_STATUS
+	sctpPrStreamStatus = C.SCTP_PR_STREAM_STATUS
+	sctpPrSctpNone     = C.SCTP_PR_SCTP_NONE
+	sctpPrSctpTTL      = C.SCTP_PR_SCTP_TTL
+	sctpPrSctpRtx      = C.SCTP_PR_SCTP_RTX
+	sctpPrSctpPrio     = C.SCTP_PR_SCTP_PRIO
+	sctpPrSctpMask     = C.SCTP_PR_SCTP_MASK
+	sctpPrSctpAll      = C.SCTP_PR_SCTP_ALL
+
+	sctpStreamScheduler      = C.SCTP_STREAM_SCHEDULER
+	sctpStreamSchedulerValue = C.SCTP_STREAM_SCHEDULER_VALUE
+	sctpSsFcfs               = C.SCTP_SS_FCFS
+	sctpSsPrio               = C.SCTP_SS_PRIO
+	sctpSsRr                 = C.SCTP_SS_RR
+	sctpSsWfq                = 4 // SCTP_SS_WFQ is not defined in old header
+
+	sctpAuthSupported     = C.SCTP_AUTH_SUPPORTED
+	sctpAuthChunk         = C.SCTP_AUTH_CHUNK
+	sctpHmacIdent         = C.SCTP_HMAC_IDENT
+	sctpAuthKey           = C.SCTP_AUTH_KEY
+	sctpAuthActiveKey     = C.SCTP_AUTH_ACTIVE_KEY
+	sctpAuthDeleteKey     = C.SCTP_AUTH_DELETE_KEY
+	sctpAuthDeactivateKey = C.SCTP_AUTH_DEACTIVATE_KEY
+	sctpPeerAuthChunks    = C.SCTP_PEER_AUTH_CHUNKS
+	sctpLocalAuthChunks   = C.SCTP_LOCAL_AUTH_CHUNKS
+
+	sctpPeerAddrParams  = C.SCTP_PEER_ADDR_PARAMS
+	sppHbEnable         = C.SPP_HB_ENABLE
+	sppHbDisable        = C.SPP_HB_DISABLE
+	sppHbDemand         = C.SPP_HB_DEMAND
+	sppPmtudEnable      = C.SPP_PMTUD_ENABLE
+	sppPmtudDisable     = C.SPP_PMTUD_DISABLE
+	sppSackdelayEnable  = C.SPP_SACKDELAY_ENABLE
+	sppSackdelayDisable = C.SPP_SACKDELAY_DISABLE
+	sppHbTimeIsZero     = C.SPP_HB_TIME_IS_ZERO
+
+	sctpAuthNewKey  = C.SCTP_AUTH_NEW_KEY
+	sctpAuthFreeKey = C.SCTP_AUTH_FREE_KEY
+	sctpAuthNoAuth  = C.SCTP_AUTH_NO_AUTH
 )
 
 type assocT C.sctp_assoc_t
 
-func setNotify(fd int) error {
+type defaultPrinfo struct {
+	assocID assocT
+	value   uint32
+	policy  uint16
+}
+
+type assocparams struct {
+	assocID     assocT
+	assocMaxRxt uint16
+	numPeerDest uint16
+	pRwnd       uint32
+	lRwnd       uint32
+	cLife       uint32
+}
+
+type paddrinfo struct {
+	assocID assocT
+	addr    sockaddrStorage
+	state   int32
+	cwnd    uint32
+	srtt    uint32
+	rto     uint32
+	mtu     uint32
+}
+
+// primAddr is struct sctp_setprim and struct sctp_setpeerprim.
+type primAddr struct {
+	assocID assocT
+	addr    sockaddrStorage
+}
+
+type status struct {
+	assocID   assocT
+	state     int32
+	rwnd      uint32
+	unackdata uint16
+	penddata  uint16
+	instrms   uint16
+	outstrms  uint16
+	fragPoint uint32
+	primary   paddrinfo
+}
+
+// struct sctp_paddrparams is packed, and IPv6 flow label and DSCP
+// that are not supported by old kernel are not used.
+func (p *paddrparams) marshal() []byte {
+	b := make([]byte, 152)
+	putUint32(b[0:], uint32(p.assocID))
+	copy(b[4:132], p.addr[:])
+	putUint32(b[132:], p.hbinterval)
+	putUint16(b[136:], p.pathmaxrxt)
+	putUint32(b[138:], p.pathmtu)
+	putUint32(b[142:], p.sackdelay)
+	putUint32(b[146:], p.flags)
+	return b
+}
+
+func (p *paddrparams) unmarshal(b []byte) {
+	p.assocID = assocT(getUint32(b[0:]))
+	copy(p.addr[:], b[4:132])
+	p.hbinterval = getUint32(b[132:])
+	p.pathmaxrxt = getUint16(b[136:])
+	p.pathmtu = getUint32(b[138:])
+	p.sackdelay = getUint32(b[142:])
+	p.flags = getUint32(b[146:])
+}
+
+// defaultNotifications is subscribed if SCTPDialer does not specify.
+const defaultNotifications = NotifyAll
+
+// setEvents subscribes data io event and notifications n
+// by the legacy SCTP_EVENTS.
+func setEvents(fd int, n Notification) error {
 	type opt struct {
 		dataIo          uint8
 		association     uint8
@@ -71,18 +249,24 @@ func setNotify(fd int) error {
 		authentication  uint8
 		senderDry       uint8
 	}
+	on := func(f Notification) uint8 {
+		if n&f != 0 {
+			return 1
+		}
+		return 0
+	}
 
 	event := opt{
 		dataIo:          1,
-		association:     1,
-		address:         1,
-		sendFailed:      1,
-		peerError:       1,
-		shutdown:        1,
-		partialDelivery: 1,
-		adaptationLayer: 1,
-		authentication:  1,
-		senderDry:       1}
+		association:     on(NotifyAssocChange),
+		address:         on(NotifyPeerAddrChange),
+		sendFailed:      on(NotifySendFailed),
+		peerError:       on(NotifyRemoteError),
+		shutdown:        on(NotifyShutdown),
+		partialDelivery: on(NotifyPartialDelivery),
+		adaptationLayer: on(NotifyAdaptation),
+		authentication:  on(NotifyAuthentication),
+		senderDry:       on(NotifySenderDry)}
 	l := unsafe.Sizeof(event)
 	p := unsafe.Pointer(&event)
 
@@ -102,17 +286,51 @@ func setSockOpt(fd, opt int, p unsafe.Pointer, l uintptr) error {
 	return nil
 }
 
-func sockOpenV4() (int, error) {
+func getSockOpt(fd, opt int, p unsafe.Pointer, l *uintptr) error {
+	sl := C.socklen_t(*l)
+	n, e := C.getsockopt(
+		C.int(fd),
+		C.SOL_SCTP,
+		C.int(opt),
+		p,
+		&sl)
+	if int(n) < 0 {
+		return e
+	}
+	*l = uintptr(sl)
+	return nil
+}
+
+func enableInterleaving(fd int) error {
+	attr := int32(2)
+	l := unsafe.Sizeof(attr)
+	p := unsafe.Pointer(&attr)
+
+	if e := setSockOpt(fd, C.SCTP_FRAGMENT_INTERLEAVE, p, l); e != nil {
+		return e
+	}
+	return setAssocValue(fd, C.SCTP_INTERLEAVING_SUPPORTED, 0, 1)
+}
+
+func getSockBuf(fd, opt int) (int, error) {
+	return syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, opt)
+}
+
+func setSockBuf(fd, opt, v int) error {
+	return syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, opt, v)
+}
+
+func sockOpenV4(st int) (int, error) {
 	return syscall.Socket(
 		syscall.AF_INET,
-		syscall.SOCK_SEQPACKET,
+		st,
 		C.IPPROTO_SCTP)
 }
 
-func sockOpenV6() (int, error) {
+func sockOpenV6(st int) (int, error) {
 	return syscall.Socket(
 		syscall.AF_INET6,
-		syscall.SOCK_SEQPACKET,
+		st,
 		C.IPPROTO_SCTP)
 }
 
@@ -120,16 +338,133 @@ func sockListen(fd int) error {
 	return syscall.Listen(fd, BacklogSize)
 }
 
+func sockAccept(fd int) (int, error) {
+	nfd, _, e := syscall.Accept(fd)
+	return nfd, e
+}
+
 func sockClose(fd int) error {
 	return syscall.Close(fd)
 }
 
-func sctpBindx(fd int, ptr unsafe.Pointer, l int) error {
+// sockShutdown starts shutdown of the association on one-to-one style socket.
+func sockShutdown(fd int) error {
+	return syscall.Shutdown(fd, syscall.SHUT_WR)
+}
+
+// sockAbort makes close of one-to-one style socket abort the association.
+func sockAbort(fd int) error {
+	return syscall.SetsockoptLinger(fd, syscall.SOL_SOCKET, syscall.SO_LINGER,
+		&syscall.Linger{Onoff: 1, Linger: 0})
+}
+
+// sockFile is the nonblocking socket that is handled by
+// the runtime network poller.
+type sockFile struct {
+	sock int
+	f    *os.File
+	rc   syscall.RawConn
+	wm   sync.Mutex
+	wds  map[*time.Time]bool
+}
+
+func newSockFile(fd int) (*sockFile, error) {
+	if e := syscall.SetNonblock(fd, true); e != nil {
+		return nil, e
+	}
+	f := os.NewFile(uintptr(fd), "sctp")
+	rc, e := f.SyscallConn()
+	if e != nil {
+		f.Close()
+		return nil, e
+	}
+	return &sockFile{sock: fd, f: f, rc: rc}, nil
+}
+
+// read calls fn until it does not return EAGAIN,
+// it waits the socket is readable.
+func (s *sockFile) read(fn func(fd int) error) error {
+	var e error
+	if re := s.rc.Read(func(fd uintptr) bool {
+		e = fn(int(fd))
+		return e != syscall.EAGAIN
+	}); re != nil {
+		return re
+	}
+	return e
+}
+
+// write calls fn until it does not return EAGAIN,
+// it waits the socket is writable until t.
+// Writers of the associations on the same socket do not block each other,
+// deadline of the socket is the earliest deadline of the writers.
+func (s *sockFile) write(t time.Time, fn func(fd int) error) error {
+	s.wm.Lock()
+	if s.wds == nil {
+		s.wds = make(map[*time.Time]bool)
+	}
+	s.wds[&t] = true
+	s.wm.Unlock()
+	defer func() {
+		s.wm.Lock()
+		delete(s.wds, &t)
+		s.wm.Unlock()
+	}()
+
+	for {
+		if !t.IsZero() && !time.Now().Before(t) {
+			return os.ErrDeadlineExceeded
+		}
+		if e := s.setDeadline(); e != nil {
+			return e
+		}
+
+		var e error
+		we := s.rc.Write(func(fd uintptr) bool {
+			e = fn(int(fd))
+			return e != syscall.EAGAIN
+		})
+		if we == nil {
+			return e
+		}
+		// deadline of the other writer is exceeded
+		if !errors.Is(we, os.ErrDeadlineExceeded) {
+			return we
+		}
+	}
+}
+
+// setDeadline sets the earliest deadline of the writers
+// that is not exceeded yet.
+func (s *sockFile) setDeadline() error {
+	s.wm.Lock()
+	defer s.wm.Unlock()
+
+	now := time.Now()
+	var d time.Time
+	for t := range s.wds {
+		if now.Before(*t) && (d.IsZero() || t.Before(d)) {
+			d = *t
+		}
+	}
+	return s.f.SetWriteDeadline(d)
+}
+
+// close closes the socket and wakes up blocked read and write.
+func (s *sockFile) close() error {
+	return s.f.Close()
+}
+
+func (s *sockFile) rawConn() (syscall.RawConn, error) {
+	return s.rc, nil
+}
+
+func sctpBindx(fd int, ptr unsafe.Pointer, l, flag int) error {
 	n, e := C.sctp_bindx(
 		C.int(fd),
 		(*C.struct_sockaddr)(ptr),
 		C.int(l),
-		C.SCTP_BINDX_ADD_ADDR)
+		C.int(flag))
 	if int(n) < 0 {
 		return e
 	}
@@ -149,6 +484,16 @@ func sctpConnectx(fd int, ptr unsafe.Pointer, l int) (assocT, error) {
 	return t, nil
 }
 
+func sctpPeeloff(fd int, id assocT) (int, error) {
+	n, e := C.sctp_peeloff(
+		C.int(fd),
+		C.sctp_assoc_t(id))
+	if int(n) < 0 {
+		return -1, e
+	}
+	return int(n), nil
+}
+
 func sctpSend(fd int, b []byte, info *sndrcvInfo, flag int) (int, error) {
 	buf := unsafe.Pointer(nil)
 	if len(b) > 0 {
@@ -166,27 +511,26 @@ func sctpSend(fd int, b []byte, info *sndrcvInfo, flag int) (int, error) {
 	return int(n), nil
 }
 
-func sctpPolling(fd int) error {
-	addr := &syscall.RawSockaddrInet4{
-		Family: syscall.AF_INET,
-		Port:   0,
-		Addr:   [4]byte{0x7f, 0x00, 0x00, 0x01}}
+func sctpSendmsg(fd int, b []byte, ptr unsafe.Pointer, l uintptr, info *sndrcvInfo) (int, error) {
+	buf := unsafe.Pointer(nil)
+	if len(b) > 0 {
+		buf = unsafe.Pointer(&b[0])
+	}
 	n, e := C.sctp_sendmsg(
 		C.int(fd),
-		unsafe.Pointer(&[]byte{0x00}[0]),
-		C.size_t(1),
-		(*C.struct_sockaddr)(unsafe.Pointer(addr)),
-		C.socklen_t(unsafe.Sizeof(*addr)),
-		C.uint32_t(CloseNotifyPpid),   // ppid
-		C.uint32_t(0),                 // flags
-		C.uint16_t(0),                 // stream_no
-		C.uint32_t(0),                 // timetolive
-		C.uint32_t(CloseNotifyCotext)) // context
-
+		buf,
+		C.size_t(len(b)),
+		(*C.struct_sockaddr)(ptr),
+		C.socklen_t(l),
+		C.uint32_t(info.ppid),
+		C.uint32_t(info.flags),
+		C.uint16_t(info.stream),
+		C.uint32_t(info.timetolive),
+		C.uint32_t(info.context))
 	if int(n) < 0 {
-		return e
+		return -1, e
 	}
-	return nil
+	return int(n), nil
 }
 
 func sctpRecvmsg(fd int, b []byte, info *sndrcvInfo, flag *int) (int, error) {
@@ -235,3 +579,26 @@ func sctpGetpaddrs(fd int, id assocT) (unsafe.Pointer, int, error) {
 func sctpFreepaddrs(addr unsafe.Pointer) {
 	C.sctp_freepaddrs((*C.struct_sockaddr)(addr))
 }
+
+// causeCode converts cause code in notification to host byte order,
+// Linux notifies the cause code in network byte order.
+func causeCode(v uint16) uint16 {
+	return getCause((*[2]byte)(unsafe.Pointer(&v))[:])
+}
+
+// abortCauses returns error causes in sac_info of association change,
+// Linux stores the causes without the chunk header.
+// ABORT chunk without error causes is not distinguished from
+// the lost association, then nil is returned for both.
+func abortCauses(info []byte) []byte {
+	if len(info) == 0 {
+		return nil
+	}
+	return info
+}
+
+// remoteCauses returns error causes in sre_data of remote error,
+// Linux notifies each cause with the cause code and the information.
+func remoteCauses(code uint16, data []byte) ErrorCauses {
+	return ErrorCauses{newErrorCause(code, data)}
+}
@@ -1,24 +1,33 @@
 package extnet
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"sync"
 	"syscall"
+	"time"
 	"unsafe"
 )
 
 // SCTPListener is a SCTP network listener.
 type SCTPListener struct {
-	sock   int
-	ppid   uint32
-	uo     uint16
-	con    map[assocT]*SCTPConn
-	accept chan *SCTPConn
-	m      sync.Mutex
-	close  chan bool
+	sctpSocket
+	oneToOne  bool
+	listening bool
+	ppid      uint32
+	uo        uint16
+	timeout   time.Duration
+	con       map[assocT]*SCTPConn
+	cm        sync.Mutex
+	accept    chan *SCTPConn
+	failed    chan assocT
+	closed    bool
+	done      chan bool
+	subs      map[*subscriber]bool
+	sm        sync.Mutex
 }
 
 // Accept implements the Accept method in the Listener interface;
@@ -28,36 +37,63 @@ func (l *SCTPListener) Accept() (net.Conn, error) {
 }
 
 // AcceptSCTP accepts the next incoming call and returns the new connection.
-func (l *SCTPListener) AcceptSCTP() (c *SCTPConn, e error) {
-	l.m.Lock()
-	defer l.m.Unlock()
+func (l *SCTPListener) AcceptSCTP() (*SCTPConn, error) {
+	return l.AcceptContext(context.Background())
+}
 
-	if l.close == nil {
-		c = <-l.accept
+// AcceptContext accepts the next incoming call until the context is done.
+func (l *SCTPListener) AcceptContext(ctx context.Context) (c *SCTPConn, e error) {
+	if !l.isClosed() {
+		select {
+		case c = <-l.accept:
+		case <-l.done:
+		case <-ctx.Done():
+			e = ctx.Err()
+		}
+	}
+	if c == nil && e == nil {
+		e = errors.New("socket is closed")
 	}
-	if c == nil {
+	if e != nil {
+		c = nil
 		e = &net.OpError{
 			Op:   "accept",
 			Net:  "sctp",
 			Addr: l.Addr(),
-			Err:  errors.New("socket is closed")}
+			Err:  e}
 	}
 	return
 }
 
 // Close stops listening on the SCTP address.
 func (l *SCTPListener) Close() (e error) {
-	if l.close == nil {
-		l.close = make(chan bool)
-		sctpPolling(l.sock)
-		<-l.close
+	if !l.setClosed() {
+		l.file().close()
+		<-l.done
 	}
 	return
 }
 
+// isClosed returns true if the listener does not accept new associations,
+// it is closed or it is the listener of the dialed connection.
+func (l *SCTPListener) isClosed() bool {
+	l.cm.Lock()
+	defer l.cm.Unlock()
+	return l.closed
+}
+
+// setClosed marks the listener closed and returns the previous state.
+func (l *SCTPListener) setClosed() bool {
+	l.cm.Lock()
+	defer l.cm.Unlock()
+	r := l.closed
+	l.closed = true
+	return r
+}
+
 // Addr returns the listener's network address, a *SCTPAddr.
 func (l *SCTPListener) Addr() net.Addr {
-	ptr, n, e := sctpGetladdrs(l.sock, 0)
+	ptr, n, e := sctpGetladdrs(l.sock(), 0)
 	if e != nil {
 		return nil
 	}
@@ -81,7 +117,7 @@ func (l *SCTPListener) Connect(addr net.Addr) error {
 
 // ConnectSCTP create new connection of this listener
 func (l *SCTPListener) ConnectSCTP(raddr *SCTPAddr) error {
-	if l.close != nil {
+	if l.isClosed() {
 		return &net.OpError{
 			Op:     "connect",
 			Net:    "sctp",
@@ -89,10 +125,21 @@ func (l *SCTPListener) ConnectSCTP(raddr *SCTPAddr) error {
 			Addr:   raddr,
 			Err:    errors.New("socket is closed")}
 	}
+	if l.oneToOne {
+		return &net.OpError{
+			Op:     "connect",
+			Net:    "sctp",
+			Source: l.Addr(),
+			Addr:   raddr,
+			Err:    errors.New("not supported on one-to-one style listener")}
+	}
 
 	// connect SCTP connection to raddr
 	ptr, n := raddr.rawAddr()
-	_, e := sctpConnectx(l.sock, ptr, n)
+	_, e := sctpConnectx(l.sock(), ptr, n)
+	if e == syscall.EINPROGRESS {
+		e = nil
+	}
 	if e != nil {
 		return &net.OpError{
 			Op:     "connect",
@@ -104,8 +151,65 @@ func (l *SCTPListener) ConnectSCTP(raddr *SCTPAddr) error {
 	return nil
 }
 
+// AddLocalAddr adds local addresses to the listener.
+// Existing associations are informed of the addresses by ASCONF only if
+// the system enables it, such as net.sctp.addip_enable and SCTP-AUTH
+// on Linux, it is not enabled by this method.
+// The local address change is not notified as an event.
+func (l *SCTPListener) AddLocalAddr(addr *SCTPAddr) error {
+	if l.isClosed() {
+		return l.closedError("addaddr", addr)
+	}
+	return l.bindx("addaddr", addr, sctpBindxAddAddr)
+}
+
+// RemoveLocalAddr removes local addresses from the listener,
+// see AddLocalAddr for ASCONF.
+func (l *SCTPListener) RemoveLocalAddr(addr *SCTPAddr) error {
+	if l.isClosed() {
+		return l.closedError("removeaddr", addr)
+	}
+	return l.bindx("removeaddr", addr, sctpBindxRemAddr)
+}
+
+func (l *SCTPListener) closedError(op string, addr *SCTPAddr) error {
+	return &net.OpError{
+		Op:     op,
+		Net:    "sctp",
+		Source: l.Addr(),
+		Addr:   addr,
+		Err:    errors.New("socket is closed")}
+}
+
+// bindx adds or removes local addresses of the socket,
+// the port of the socket is used if addr has no port.
+func (s *sctpSocket) bindx(op string, addr *SCTPAddr, flag int) error {
+	var la net.Addr
+	a := &SCTPAddr{IP: addr.IP, Port: addr.Port, Zone: addr.Zone}
+	if ptr, n, e := sctpGetladdrs(s.sock(), 0); e == nil {
+		r := resolveFromRawAddr(ptr, n)
+		sctpFreeladdrs(ptr)
+		if a.Port == 0 {
+			a.Port = r.Port
+		}
+		la = r
+	}
+
+	ptr, n := a.rawAddr()
+	if e := sctpBindx(s.sock(), ptr, n, flag); e != nil {
+		return &net.OpError{
+			Op:     op,
+			Net:    "sctp",
+			Source: la,
+			Addr:   a,
+			Err:    e}
+	}
+	return nil
+}
+
 // SctpHandlerStart is the error type that indicate start sctp message handler.
 type SctpHandlerStart struct {
+	EventTime
 	Addr net.Addr
 }
 
@@ -116,8 +220,13 @@ func (e *SctpHandlerStart) Error() string {
 	return fmt.Sprintf("start sctp message handler on %s", e.Addr)
 }
 
+func (e *SctpHandlerStart) AssocID() int {
+	return 0
+}
+
 // SctpHandlerStop is the error type that indicate stop sctp message handler.
 type SctpHandlerStop struct {
+	EventTime
 	Addr net.Addr
 	Err  error
 }
@@ -134,8 +243,13 @@ func (e *SctpHandlerStop) Error() string {
 		e.Addr, e.Err)
 }
 
+func (e *SctpHandlerStop) AssocID() int {
+	return 0
+}
+
 // SctpHandlerError is the error type that indicate failure in message handler.
 type SctpHandlerError struct {
+	EventTime
 	Addr net.Addr
 	Err  error
 }
@@ -147,11 +261,58 @@ func (e *SctpHandlerError) Error() string {
 	return fmt.Sprintf("message handling failed on %s: %s", e.Addr, e.Err)
 }
 
-// read data from buffer
-func read(l *SCTPListener, ready chan bool) {
-	if Notificator != nil {
-		Notificator(&SctpHandlerStart{Addr: l.Addr()})
+func (e *SctpHandlerError) AssocID() int {
+	return 0
+}
+
+// accept new socket of one-to-one style
+func accept(l *SCTPListener, ready chan bool) {
+	l.notify(&SctpHandlerStart{Addr: l.Addr()})
+
+	ready <- true
+	for {
+		var sock int
+		e := l.file().read(func(fd int) (e error) {
+			sock, e = sockAccept(fd)
+			return
+		})
+		var f *sockFile
+		if e == nil {
+			if f, e = newSockFile(sock); e != nil {
+				sockClose(sock)
+			}
+		}
+		if e != nil {
+			eno, ok := e.(syscall.Errno)
+			if ok && eno.Temporary() {
+				l.notify(&SctpHandlerError{
+					Addr: l.Addr(), Err: e})
+				continue
+			} else {
+				l.notify(&SctpHandlerStop{
+					Addr: l.Addr(), Err: e})
+				break
+			}
+		}
+		if l.isClosed() {
+			f.close()
+			l.notify(&SctpHandlerStop{Addr: l.Addr()})
+			break
+		}
+
+		r := make(chan bool)
+		go read(l, f, r)
+		<-r
 	}
+	l.file().close()
+	l.setClosed()
+	l.unsubscribe(0)
+	close(l.done)
+}
+
+// read data from buffer
+func read(l *SCTPListener, f *sockFile, ready chan bool) {
+	l.notify(&SctpHandlerStart{Addr: l.Addr()})
 
 	type sctpTlv struct {
 		snType   uint16
@@ -166,30 +327,36 @@ func read(l *SCTPListener, ready chan bool) {
 		flag := 0
 
 		// receive message
-		n, e := sctpRecvmsg(l.sock, buf, &info, &flag)
+		n := 0
+		e := f.read(func(fd int) (e error) {
+			n, e = sctpRecvmsg(fd, buf, &info, &flag)
+			return
+		})
 		if e != nil {
-			eno, ok := e.(*syscall.Errno)
+			eno, ok := e.(syscall.Errno)
 			if ok && eno.Temporary() {
-				if Notificator != nil {
-					Notificator(&SctpHandlerError{
-						Addr: l.Addr(), Err: e})
-				}
+				l.notify(&SctpHandlerError{
+					Addr: l.Addr(), Err: e})
 				continue
 			} else {
-				if Notificator != nil {
-					Notificator(&SctpHandlerStop{
-						Addr: l.Addr(), Err: e})
-				}
+				l.notify(&SctpHandlerStop{
+					Addr: l.Addr(), Err: e})
 				break
 			}
 		}
+		if n == 0 {
+			// receive side of the socket is shut down
+			l.notify(&SctpHandlerStop{Addr: l.Addr()})
+			break
+		}
 
 		// check message type is notify
+		closed := false
 		if flag&msgNotification == msgNotification {
 			tlv := (*sctpTlv)(unsafe.Pointer(&buf[0]))
 			switch tlv.snType {
 			case sctpAssocChange:
-				l.assocChangeNotify(buf[:n])
+				closed = l.assocChangeNotify(f, buf[:n])
 			case sctpPeerAddrChange:
 				l.paddrChangeNotify(buf[:n])
 			case sctpRemoteError:
@@ -204,46 +371,91 @@ func read(l *SCTPListener, ready chan bool) {
 				l.partialDeliveryNotify(buf[:n])
 			case sctpSenderDryEvent:
 				l.senderDryNotify(buf[:n])
+			case sctpAuthenticationEvent:
+				l.authNotify(buf[:n])
+			case sctpStreamResetEvent:
+				l.streamResetNotify(buf[:n])
+			case sctpAssocResetEvent:
+				l.assocResetNotify(buf[:n])
+			case sctpStreamChangeEvent:
+				l.streamChangeNotify(buf[:n])
 			default:
 				panic(fmt.Sprintf(
 					"unknown notification type %d",
 					tlv.snType))
 			}
 		} else {
-			if Notificator != nil {
-				Notificator(&SctpRecieveData{
-					ID:        int(info.assocID),
-					Stream:    int(info.stream),
-					PPID:      int(info.ppid),
-					Unordered: info.flags&sctpUnordered == sctpUnordered,
-					Data:      buf[:n]})
-			}
+			l.notify(&SctpRecieveData{
+				ID:        int(info.assocID),
+				Stream:    int(info.stream),
+				PPID:      int(info.ppid),
+				Unordered: info.flags&sctpUnordered == sctpUnordered,
+				Data:      buf[:n]})
 			// matching exist connection
-			if p, ok := l.con[info.assocID]; ok {
-				p.queue(buf[:n], nil)
+			if p, ok := l.conn(info.assocID); ok {
+				p.queue(buf[:n], &info, flag&msgEoR == msgEoR)
 			} else {
 				panic(fmt.Sprintf(
 					"data recieved from unknown assoc id %d",
 					info.assocID))
 			}
 		}
+
+		// socket of one-to-one style association is not used any more
+		if closed && f.sock != l.sock() {
+			l.notify(&SctpHandlerStop{Addr: l.Addr()})
+			break
+		}
 	}
 
-	for _, c := range l.con {
-		c.queue(nil, io.EOF)
+	var ids []assocT
+	l.cm.Lock()
+	for id, c := range l.con {
+		if c.sock() == f.sock {
+			delete(l.con, id)
+			c.fail(io.EOF)
+			ids = append(ids, id)
+		}
+	}
+	l.cm.Unlock()
+	f.close()
+	for _, id := range ids {
+		l.unsubscribe(id)
 	}
-	sockClose(l.sock)
 
-	if l.close == nil {
-		l.close = make(chan bool)
-	} else {
-		l.close <- true
+	// subscribers of the listener are stopped only when the listener
+	// is closed, not when the socket of the peeled off association is closed
+	if f.sock != l.sock() {
+		return
 	}
+	l.setClosed()
+	l.unsubscribe(0)
+	close(l.done)
+}
+
+func (l *SCTPListener) conn(id assocT) (c *SCTPConn, ok bool) {
+	l.cm.Lock()
+	defer l.cm.Unlock()
+	c, ok = l.con[id]
+	return
+}
+
+// count returns number of the associations on the socket.
+func (l *SCTPListener) count(sock int) (n int) {
+	l.cm.Lock()
+	defer l.cm.Unlock()
+	for _, c := range l.con {
+		if c.sock() == sock {
+			n++
+		}
+	}
+	return
 }
 
 // SctpRecieveData is the error type that indicate
 // recieve data form the association.
 type SctpRecieveData struct {
+	EventTime
 	ID        int
 	Stream    int
 	PPID      int
@@ -267,3 +479,7 @@ func (e *SctpRecieveData) Error() string {
 		"recieve data from assoc(id=%d, stream=%d, ppid=%s%s): % x",
 		e.ID, e.Stream, s, uo, e.Data)
 }
+
+func (e *SctpRecieveData) AssocID() int {
+	return e.ID
+}
@@ -255,3 +255,72 @@ func TestAcceptConnect(t *testing.T) {
 		t.Errorf("close faied: %s", e)
 	}
 }
+
+func TestAddRemoveLocalAddr(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	if len(a0.IP) < 2 {
+		t.Skip("multiple address is not available")
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	l0, e := ListenSCTP("sctp", &SCTPAddr{IP: a0.IP[:1], Port: a0.Port})
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+
+	e = l0.AddLocalAddr(&SCTPAddr{IP: a0.IP[1:]})
+	if e != nil {
+		t.Errorf("add address failed: %s", e)
+	}
+	s := l0.Addr().String()
+	if s != testAddrs[0] {
+		t.Errorf("output %s is not same as %s", s, testAddrs[0])
+	}
+
+	c1, e := DialSCTP(a1, a0)
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+
+	_, e = l0.Accept()
+	if e != nil {
+		t.Errorf("accept faied: %s", e)
+	}
+
+	e = l0.RemoveLocalAddr(&SCTPAddr{IP: a0.IP[1:]})
+	if e != nil {
+		t.Errorf("remove address failed: %s", e)
+	}
+
+	// address of dialed connection
+	if len(a1.IP) > 1 {
+		if e = c1.RemoveLocalAddr(&SCTPAddr{IP: a1.IP[1:]}); e != nil {
+			t.Errorf("remove address failed: %s", e)
+		}
+		if e = c1.AddLocalAddr(&SCTPAddr{IP: a1.IP[1:]}); e != nil {
+			t.Errorf("add address failed: %s", e)
+		}
+	}
+	a := l0.Addr().(*SCTPAddr)
+	if len(a.IP) != 1 || !a.IP[0].Equal(a0.IP[0]) {
+		t.Errorf("output %s is not same as %s", a, a0.IP[0])
+	}
+
+	e = c1.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+
+	e = l0.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
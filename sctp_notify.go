@@ -1,30 +1,238 @@
 package extnet
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
-	"io"
 	"net"
-	"syscall"
+	"strings"
+	"time"
 	"unsafe"
 )
 
+// Cause codes of SCTP error causes (RFC 4960)
+const (
+	CauseInvalidStream         uint16 = 1
+	CauseMissingParameter      uint16 = 2
+	CauseStaleCookie           uint16 = 3
+	CauseOutOfResource         uint16 = 4
+	CauseUnresolvableAddr      uint16 = 5
+	CauseUnrecognizedChunk     uint16 = 6
+	CauseInvalidParameter      uint16 = 7
+	CauseUnrecognizedParameter uint16 = 8
+	CauseNoUserData            uint16 = 9
+	CauseCookieWhileShutdown   uint16 = 10
+	CauseRestartWithNewAddr    uint16 = 11
+	CauseUserInitiatedAbort    uint16 = 12
+	CauseProtocolViolation     uint16 = 13
+)
+
 var sctpErrorMap = map[uint16]error{
-	0x100: fmt.Errorf("Invalid Stream Identifier"),
-	0x200: fmt.Errorf("Missing Mandatory Parameter"),
-	0x300: fmt.Errorf("Stale Cookie Error"),
-	0x400: fmt.Errorf("Out of Resource"),
-	0x500: fmt.Errorf("Unresolvable Address"),
-	0x600: fmt.Errorf("Unrecognized Chunk Type"),
-	0x700: fmt.Errorf("Invalid Mandatory Parameter"),
-	0x800: fmt.Errorf("Unrecognized Parameters"),
-	0x900: fmt.Errorf("No User Data"),
-	0xa00: fmt.Errorf("Cookie Received While Shutting Down"),
-	0xb00: fmt.Errorf("Restart of an Association with New Addresses"),
-	0xc00: fmt.Errorf("User Initiated Abort"),
-	0xd00: fmt.Errorf("Protocol Violation")}
+	CauseInvalidStream:         fmt.Errorf("Invalid Stream Identifier"),
+	CauseMissingParameter:      fmt.Errorf("Missing Mandatory Parameter"),
+	CauseStaleCookie:           fmt.Errorf("Stale Cookie Error"),
+	CauseOutOfResource:         fmt.Errorf("Out of Resource"),
+	CauseUnresolvableAddr:      fmt.Errorf("Unresolvable Address"),
+	CauseUnrecognizedChunk:     fmt.Errorf("Unrecognized Chunk Type"),
+	CauseInvalidParameter:      fmt.Errorf("Invalid Mandatory Parameter"),
+	CauseUnrecognizedParameter: fmt.Errorf("Unrecognized Parameters"),
+	CauseNoUserData:            fmt.Errorf("No User Data"),
+	CauseCookieWhileShutdown:   fmt.Errorf("Cookie Received While Shutting Down"),
+	CauseRestartWithNewAddr:    fmt.Errorf("Restart of an Association with New Addresses"),
+	CauseUserInitiatedAbort:    fmt.Errorf("User Initiated Abort"),
+	CauseProtocolViolation:     fmt.Errorf("Protocol Violation")}
+
+// Errors of the closed association, AssocError wraps one of them.
+var (
+	// ErrAborted is the error that the association is aborted.
+	ErrAborted = errors.New("association is aborted")
+	// ErrShutdown is the error that the association is shut down.
+	ErrShutdown = errors.New("association is shut down")
+	// ErrCommLost is the error that the association is lost
+	// without any cause, such as retransmission timeout.
+	ErrCommLost = errors.New("association is lost")
+)
+
+// AssocError is the error of the closed association.
+// Read returns io.EOF instead of AssocError if Err is ErrShutdown.
+type AssocError struct {
+	Err    error
+	Cause  uint16
+	Reason string
+}
+
+func (e *AssocError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	s := e.Err.Error()
+	if c, ok := sctpErrorMap[e.Cause]; ok {
+		s = fmt.Sprintf("%s: %s", s, c)
+	}
+	if e.Reason != "" {
+		s = fmt.Sprintf("%s: %s", s, e.Reason)
+	}
+	return s
+}
+
+// Unwrap returns ErrAborted, ErrShutdown or ErrCommLost.
+func (e *AssocError) Unwrap() error {
+	return e.Err
+}
+
+// abortError returns AssocError of the cause code and
+// the error causes in ABORT chunk.
+// info is nil if the association is lost without ABORT chunk,
+// ABORT chunk without error causes is empty but not nil.
+func abortError(code uint16, info []byte) *AssocError {
+	e := &AssocError{Err: ErrCommLost, Cause: code}
+	if code != 0 || info != nil {
+		e.Err = ErrAborted
+	}
+	for _, c := range parseCauses(info) {
+		if c.Code == CauseUserInitiatedAbort {
+			e.Reason = c.Reason
+			break
+		}
+	}
+	return e
+}
+
+// getCause returns 2 bytes value in network byte order.
+func getCause(b []byte) uint16 {
+	return uint16(b[0])<<8 | uint16(b[1])
+}
+
+// ErrorCause is an error cause in ERROR or ABORT chunk.
+// Cause-specific fields are set by the cause code,
+// and Info is the raw cause-specific information.
+type ErrorCause struct {
+	Code uint16
+
+	// Stream is the stream of Invalid Stream Identifier.
+	Stream uint16
+	// Params are parameter types of Missing Mandatory Parameter.
+	Params []uint16
+	// Staleness is the staleness of Stale Cookie Error.
+	Staleness time.Duration
+	// Chunk is the chunk of Unrecognized Chunk Type.
+	Chunk []byte
+	// TSN is the TSN of No User Data.
+	TSN uint32
+	// Reason is the reason of User Initiated Abort or
+	// the information of Protocol Violation.
+	Reason string
+
+	Info []byte
+}
+
+// Name returns the name of the cause code.
+func (c *ErrorCause) Name() string {
+	if e, ok := sctpErrorMap[c.Code]; ok {
+		return e.Error()
+	}
+	return fmt.Sprintf("Unknown Cause(%d)", c.Code)
+}
+
+func (c *ErrorCause) Error() string {
+	if c == nil {
+		return "<nil>"
+	}
+	switch c.Code {
+	case CauseInvalidStream:
+		return fmt.Sprintf("%s(stream=%d)", c.Name(), c.Stream)
+	case CauseMissingParameter:
+		return fmt.Sprintf("%s(types=%v)", c.Name(), c.Params)
+	case CauseStaleCookie:
+		return fmt.Sprintf("%s(staleness=%s)", c.Name(), c.Staleness)
+	case CauseUnrecognizedChunk:
+		if len(c.Chunk) != 0 {
+			return fmt.Sprintf("%s(type=%d)", c.Name(), c.Chunk[0])
+		}
+	case CauseNoUserData:
+		return fmt.Sprintf("%s(tsn=%d)", c.Name(), c.TSN)
+	case CauseUserInitiatedAbort, CauseProtocolViolation:
+		if c.Reason != "" {
+			return fmt.Sprintf("%s(%s)", c.Name(), c.Reason)
+		}
+	}
+	return c.Name()
+}
+
+// newErrorCause decodes cause-specific information of the cause code.
+func newErrorCause(code uint16, info []byte) *ErrorCause {
+	c := &ErrorCause{Code: code, Info: info}
+	switch code {
+	case CauseInvalidStream:
+		if len(info) >= 2 {
+			c.Stream = binary.BigEndian.Uint16(info)
+		}
+	case CauseMissingParameter:
+		if len(info) >= 4 {
+			n := int(binary.BigEndian.Uint32(info))
+			for i := 4; i+2 <= len(info) && len(c.Params) < n; i += 2 {
+				c.Params = append(c.Params, binary.BigEndian.Uint16(info[i:]))
+			}
+		}
+	case CauseStaleCookie:
+		if len(info) >= 4 {
+			c.Staleness = time.Duration(binary.BigEndian.Uint32(info)) * time.Microsecond
+		}
+	case CauseUnrecognizedChunk:
+		c.Chunk = info
+	case CauseNoUserData:
+		if len(info) >= 4 {
+			c.TSN = binary.BigEndian.Uint32(info)
+		}
+	case CauseUserInitiatedAbort, CauseProtocolViolation:
+		c.Reason = strings.TrimRight(string(info), "\x00")
+	}
+	return c
+}
+
+// parseCauses decodes TLVs of error causes.
+func parseCauses(b []byte) ErrorCauses {
+	var r ErrorCauses
+	for len(b) >= 4 {
+		l := int(getCause(b[2:4]))
+		if l < 4 || l > len(b) {
+			break
+		}
+		r = append(r, newErrorCause(getCause(b[0:2]), b[4:l]))
+
+		// cause is padded to 4 bytes
+		l = (l + 3) &^ 3
+		if l > len(b) {
+			l = len(b)
+		}
+		b = b[l:]
+	}
+	return r
+}
+
+// ErrorCauses is the list of error causes.
+type ErrorCauses []*ErrorCause
+
+func (c ErrorCauses) Error() string {
+	s := make([]string, len(c))
+	for i, e := range c {
+		s[i] = e.Error()
+	}
+	return strings.Join(s, ", ")
+}
+
+// As sets the first cause to target if target is **ErrorCause.
+func (c ErrorCauses) As(target interface{}) bool {
+	if p, ok := target.(**ErrorCause); ok && len(c) != 0 {
+		*p = c[0]
+		return true
+	}
+	return false
+}
 
 // SctpAssocUp is the error type that indicate new association is ready.
 type SctpAssocUp struct {
+	EventTime
 	ID      int
 	OStream int
 	IStream int
@@ -38,8 +246,13 @@ func (e *SctpAssocUp) Error() string {
 		"a new association(id=%d) is ready", e.ID)
 }
 
+func (e *SctpAssocUp) AssocID() int {
+	return e.ID
+}
+
 // SctpAssocLost is the error type that indicate the association has failed.
 type SctpAssocLost struct {
+	EventTime
 	ID  int
 	Err error
 }
@@ -52,9 +265,14 @@ func (e *SctpAssocLost) Error() string {
 		"the association(id=%d) has failed: %s", e.ID, e.Err)
 }
 
+func (e *SctpAssocLost) AssocID() int {
+	return e.ID
+}
+
 // SctpAssocShutdown is the error type that indicate
 // the association has gracefully closed.
 type SctpAssocShutdown struct {
+	EventTime
 	ID int
 }
 
@@ -66,9 +284,14 @@ func (e *SctpAssocShutdown) Error() string {
 		"the association(id=%d) has gracefully closed", e.ID)
 }
 
+func (e *SctpAssocShutdown) AssocID() int {
+	return e.ID
+}
+
 // SctpAssocRestart is the error type that indicate
 // SCTP has detected that the peer has restarted.
 type SctpAssocRestart struct {
+	EventTime
 	ID      int
 	OStream int
 	IStream int
@@ -82,9 +305,14 @@ func (e *SctpAssocRestart) Error() string {
 		"the association(id=%d) peer has restarted", e.ID)
 }
 
+func (e *SctpAssocRestart) AssocID() int {
+	return e.ID
+}
+
 // SctpAssocStartFail is the error type that indicate
 // the association failed to setup.
 type SctpAssocStartFail struct {
+	EventTime
 	ID int
 }
 
@@ -96,7 +324,13 @@ func (e *SctpAssocStartFail) Error() string {
 		"the association(id=%d) failed to setup", e.ID)
 }
 
-func (l *SCTPListener) assocChangeNotify(buf []byte) {
+func (e *SctpAssocStartFail) AssocID() int {
+	return e.ID
+}
+
+// assocChangeNotify returns true if the socket of one-to-one style
+// association is closed.
+func (l *SCTPListener) assocChangeNotify(f *sockFile, buf []byte) bool {
 	type ntfy struct {
 		chtype          uint16
 		flags           uint16
@@ -112,85 +346,93 @@ func (l *SCTPListener) assocChangeNotify(buf []byte) {
 
 	switch c.state {
 	case sctpCommUp:
-		if Notificator != nil {
-			Notificator(&SctpAssocUp{
-				ID:      int(c.assocID),
-				OStream: int(c.outboundStreams),
-				IStream: int(c.inboundStreams)})
-		}
+		l.notify(&SctpAssocUp{
+			ID:      int(c.assocID),
+			OStream: int(c.outboundStreams),
+			IStream: int(c.inboundStreams)})
 
-		if _, ok := l.con[c.assocID]; ok {
+		if _, ok := l.conn(c.assocID); ok {
 			panic(fmt.Sprintf(
 				"duplicate assoc id %d in new association notification",
 				c.assocID))
 		}
 
-		if l.close == nil {
+		if !l.isClosed() {
 			con := &SCTPConn{
-				l:   l,
-				id:  c.assocID,
-				buf: make([]byte, 0, RxBufferSize)}
-			con.win = con.buf
+				sctpSocket: sctpSocket{
+					id: c.assocID,
+					f:  f},
+				l:       l,
+				timeout: l.timeout}
 			con.wc.L = &con.m
 
+			l.cm.Lock()
 			l.con[c.assocID] = con
+			l.cm.Unlock()
 			l.accept <- con
 		} else {
 			info := sndrcvInfo{
 				flags:   sctpAbort,
 				assocID: c.assocID}
-			sctpSend(l.sock, []byte("closed"), &info, 0)
+			sctpSend(f.sock, []byte("closed"), &info, 0)
+			return f.sock != l.sock()
 		}
 	case sctpCommLost:
-		if Notificator != nil {
-			Notificator(&SctpAssocLost{
-				ID:  int(c.assocID),
-				Err: sctpErrorMap[c.sacError]})
-		}
-
-		if con, ok := l.con[c.assocID]; ok {
-			delete(l.con, c.assocID)
-			con.queue(nil, io.EOF)
-		}
-		if l.close != nil && len(l.con) == 0 {
-			sockClose(l.sock)
-		}
+		code := causeCode(c.sacError)
+		l.notify(&SctpAssocLost{
+			ID:  int(c.assocID),
+			Err: sctpErrorMap[code]})
+		return l.closeConn(f.sock, c.assocID,
+			abortError(code, abortCauses(buf[unsafe.Sizeof(*c):])))
 	case sctpShutdownComp:
-		if Notificator != nil {
-			Notificator(&SctpAssocShutdown{
-				ID: int(c.assocID)})
-		}
-
-		if con, ok := l.con[c.assocID]; ok {
-			delete(l.con, c.assocID)
-			con.queue(nil, io.EOF)
-		}
-		if l.close != nil && len(l.con) == 0 {
-			sockClose(l.sock)
-		}
+		l.notify(&SctpAssocShutdown{
+			ID: int(c.assocID)})
+		return l.closeConn(f.sock, c.assocID, &AssocError{Err: ErrShutdown})
 	case sctpRestart:
-		if Notificator != nil {
-			Notificator(&SctpAssocRestart{
-				ID:      int(c.assocID),
-				OStream: int(c.outboundStreams),
-				IStream: int(c.inboundStreams)})
-		}
+		l.notify(&SctpAssocRestart{
+			ID:      int(c.assocID),
+			OStream: int(c.outboundStreams),
+			IStream: int(c.inboundStreams)})
 	case sctpCantStrAssoc:
-		if Notificator != nil {
-			Notificator(&SctpAssocStartFail{
-				ID: int(c.assocID)})
+		l.notify(&SctpAssocStartFail{
+			ID: int(c.assocID)})
+		if l.failed != nil {
+			select {
+			case l.failed <- c.assocID:
+			default:
+			}
 		}
 	default:
 		panic(fmt.Sprintf(
 			"invalid state of association change notification on association %d",
 			c.assocID))
 	}
-	return
+	return false
+}
+
+func (l *SCTPListener) closeConn(sock int, id assocT, e *AssocError) bool {
+	l.cm.Lock()
+	con, ok := l.con[id]
+	delete(l.con, id)
+	l.cm.Unlock()
+
+	if ok {
+		con.closeAssoc(e)
+	}
+	l.unsubscribe(id)
+	if sock != l.sock() {
+		return true
+	}
+	if l.isClosed() && l.count(l.sock()) == 0 {
+		l.file().close()
+	}
+	return false
 }
 
 // SctpPeerAddrAvailable is the error type that indicate
 // this address is now reachable.
 type SctpPeerAddrAvailable struct {
+	EventTime
 	ID int
 	IP net.IP
 }
@@ -203,9 +445,14 @@ func (e *SctpPeerAddrAvailable) Error() string {
 		"address %s of the association(id=%d) is now reachable", e.IP, e.ID)
 }
 
+func (e *SctpPeerAddrAvailable) AssocID() int {
+	return e.ID
+}
+
 // SctpPeerAddrUnreachable is the error type that indicate
 // this address specified can no longer be reached.
 type SctpPeerAddrUnreachable struct {
+	EventTime
 	ID  int
 	IP  net.IP
 	Err uint32
@@ -219,9 +466,14 @@ func (e *SctpPeerAddrUnreachable) Error() string {
 		"address %s of the association(id=%d) can no longer be reached", e.IP, e.ID)
 }
 
+func (e *SctpPeerAddrUnreachable) AssocID() int {
+	return e.ID
+}
+
 // SctpPeerAddrRemoved is the error type that indicate
 // this address is no longer part of the association.
 type SctpPeerAddrRemoved struct {
+	EventTime
 	ID  int
 	IP  net.IP
 	Err uint32
@@ -235,9 +487,14 @@ func (e *SctpPeerAddrRemoved) Error() string {
 		"address %s is no longer part of the association(id=%d)", e.IP, e.ID)
 }
 
+func (e *SctpPeerAddrRemoved) AssocID() int {
+	return e.ID
+}
+
 // SctpPeerAddrAdded is the error type that indicate
 // this address is now part of the association.
 type SctpPeerAddrAdded struct {
+	EventTime
 	ID int
 	IP net.IP
 }
@@ -250,9 +507,14 @@ func (e *SctpPeerAddrAdded) Error() string {
 		"address %s is now part of the association(id=%d)", e.IP, e.ID)
 }
 
+func (e *SctpPeerAddrAdded) AssocID() int {
+	return e.ID
+}
+
 // SctpPeerAddrMadePrim is the error type that indicate
 // this address has now been made to be the primary destination address.
 type SctpPeerAddrMadePrim struct {
+	EventTime
 	ID int
 	IP net.IP
 }
@@ -265,9 +527,14 @@ func (e *SctpPeerAddrMadePrim) Error() string {
 		"address %s of the association(id=%d) is made to be the primary destination", e.IP, e.ID)
 }
 
+func (e *SctpPeerAddrMadePrim) AssocID() int {
+	return e.ID
+}
+
 // SctpPeerAddrConfirmed is the error type that indicate
 // this address is confirmed from peer.
 type SctpPeerAddrConfirmed struct {
+	EventTime
 	ID int
 	IP net.IP
 }
@@ -280,30 +547,24 @@ func (e *SctpPeerAddrConfirmed) Error() string {
 		"address %s of the association(id=%d) is confirmed as a valid address", e.IP, e.ID)
 }
 
+func (e *SctpPeerAddrConfirmed) AssocID() int {
+	return e.ID
+}
+
 func (l *SCTPListener) paddrChangeNotify(buf []byte) {
 	type ntfy struct {
 		chtype   uint16
 		flags    uint16
 		length   uint32
-		addr     [128]byte // sockaddrStorage
+		addr     sockaddrStorage
 		state    uint32
 		spcError uint32
 		assocID  assocT
 	}
 
 	c := (*ntfy)(unsafe.Pointer(&buf[0]))
-	var ip net.IP
-	switch (*syscall.RawSockaddrAny)(unsafe.Pointer(&c.addr[0])).Addr.Family {
-	case syscall.AF_INET:
-		a := *(*syscall.RawSockaddrInet4)(unsafe.Pointer(&c.addr[0]))
-		ip = net.IPv4(a.Addr[0], a.Addr[1], a.Addr[2], a.Addr[3])
-	case syscall.AF_INET6:
-		a := *(*syscall.RawSockaddrInet6)(unsafe.Pointer(&c.addr[0]))
-		ip = make([]byte, net.IPv6len)
-		for j := 0; j < net.IPv6len; j++ {
-			ip[j] = a.Addr[j]
-		}
-	default:
+	ip := ipFromSockaddr(unsafe.Pointer(&c.addr))
+	if ip == nil {
 		panic(fmt.Sprintf(
 			"invalid family of address change notification on association %d",
 			c.assocID))
@@ -311,43 +572,31 @@ func (l *SCTPListener) paddrChangeNotify(buf []byte) {
 
 	switch c.state {
 	case sctpAddrAvailable:
-		if Notificator != nil {
-			Notificator(&SctpPeerAddrAvailable{
-				ID: int(c.assocID),
-				IP: ip})
-		}
+		l.notify(&SctpPeerAddrAvailable{
+			ID: int(c.assocID),
+			IP: ip})
 	case sctpAddrUnreachable:
-		if Notificator != nil {
-			Notificator(&SctpPeerAddrUnreachable{
-				ID:  int(c.assocID),
-				IP:  ip,
-				Err: c.spcError})
-		}
+		l.notify(&SctpPeerAddrUnreachable{
+			ID:  int(c.assocID),
+			IP:  ip,
+			Err: c.spcError})
 	case sctpAddrRemoved:
-		if Notificator != nil {
-			Notificator(&SctpPeerAddrRemoved{
-				ID:  int(c.assocID),
-				IP:  ip,
-				Err: c.spcError})
-		}
+		l.notify(&SctpPeerAddrRemoved{
+			ID:  int(c.assocID),
+			IP:  ip,
+			Err: c.spcError})
 	case sctpAddrAdded:
-		if Notificator != nil {
-			Notificator(&SctpPeerAddrAdded{
-				ID: int(c.assocID),
-				IP: ip})
-		}
+		l.notify(&SctpPeerAddrAdded{
+			ID: int(c.assocID),
+			IP: ip})
 	case sctpAddrMadePrim:
-		if Notificator != nil {
-			Notificator(&SctpPeerAddrMadePrim{
-				ID: int(c.assocID),
-				IP: ip})
-		}
+		l.notify(&SctpPeerAddrMadePrim{
+			ID: int(c.assocID),
+			IP: ip})
 	case sctpAddrConfirmed:
-		if Notificator != nil {
-			Notificator(&SctpPeerAddrConfirmed{
-				ID: int(c.assocID),
-				IP: ip})
-		}
+		l.notify(&SctpPeerAddrConfirmed{
+			ID: int(c.assocID),
+			IP: ip})
 	default:
 		panic(fmt.Sprintf(
 			"invalid state of address change notification on association %d",
@@ -358,6 +607,7 @@ func (l *SCTPListener) paddrChangeNotify(buf []byte) {
 // SctpSendFailed is the error type that indicate
 // SCTP cannot deliver a message.
 type SctpSendFailed struct {
+	EventTime
 	ID  int
 	Err error
 }
@@ -370,6 +620,10 @@ func (e *SctpSendFailed) Error() string {
 		"message send failed on association(id=%d) reason is %s", e.ID, e.Err)
 }
 
+func (e *SctpSendFailed) AssocID() int {
+	return e.ID
+}
+
 func (l *SCTPListener) sendFailedNotify(buf []byte) {
 	type ntfy struct {
 		sstype   uint16
@@ -381,30 +635,44 @@ func (l *SCTPListener) sendFailedNotify(buf []byte) {
 		data     []byte
 	}
 	c := (*ntfy)(unsafe.Pointer(&buf[0]))
-	if c.info.ppid == CloseNotifyPpid &&
-		c.info.context == CloseNotifyCotext {
-		sockClose(l.sock)
-	}
-	if Notificator != nil {
-		Notificator(&SctpSendFailed{
-			ID:  int(c.assocID),
-			Err: sctpErrorMap[uint16(c.ssfError)]})
-	}
+	l.notify(&SctpSendFailed{
+		ID:  int(c.assocID),
+		Err: sctpErrorMap[causeCode(uint16(c.ssfError))]})
 }
 
 // SctpRemoteError is the error type that indicate
 // remote peer send an Operational Error message.
+// Err is the cause code of the first cause, and Causes are
+// all causes in the message.
 type SctpRemoteError struct {
-	ID  int
-	Err uint16
+	EventTime
+	ID     int
+	Err    uint16
+	Causes ErrorCauses
 }
 
 func (e *SctpRemoteError) Error() string {
 	if e == nil {
 		return "<nil>"
 	}
+	if len(e.Causes) == 0 {
+		return fmt.Sprintf(
+			"remote peer send error on association(id=%d)", e.ID)
+	}
 	return fmt.Sprintf(
-		"remote peer send error on association(id=%d)", e.ID)
+		"remote peer send error on association(id=%d): %s", e.ID, e.Causes)
+}
+
+func (e *SctpRemoteError) AssocID() int {
+	return e.ID
+}
+
+// Unwrap returns the causes, then a cause is available by errors.As.
+func (e *SctpRemoteError) Unwrap() error {
+	if len(e.Causes) == 0 {
+		return nil
+	}
+	return e.Causes
 }
 
 func (l *SCTPListener) remoteErrorNotify(buf []byte) {
@@ -414,18 +682,19 @@ func (l *SCTPListener) remoteErrorNotify(buf []byte) {
 		length   uint32
 		sreError uint16
 		assocID  assocT
-		data     []byte
 	}
 	c := (*ntfy)(unsafe.Pointer(&buf[0]))
-	if Notificator != nil {
-		Notificator(&SctpRemoteError{
-			ID: int(c.assocID)})
-	}
+	code := causeCode(c.sreError)
+	l.notify(&SctpRemoteError{
+		ID:     int(c.assocID),
+		Err:    code,
+		Causes: remoteCauses(code, buf[unsafe.Sizeof(*c):])})
 }
 
 // SctpShutdown is the error type that indicate
 // the association is required shutdown.
 type SctpShutdown struct {
+	EventTime
 	ID int
 }
 
@@ -437,6 +706,10 @@ func (e *SctpShutdown) Error() string {
 		"association(id=%d) is required shutdown", e.ID)
 }
 
+func (e *SctpShutdown) AssocID() int {
+	return e.ID
+}
+
 func (l *SCTPListener) shutdownNotify(buf []byte) {
 	type ntfy struct {
 		chtype  uint16
@@ -446,17 +719,22 @@ func (l *SCTPListener) shutdownNotify(buf []byte) {
 	}
 
 	c := (*ntfy)(unsafe.Pointer(&buf[0]))
-	if Notificator != nil {
-		Notificator(&SctpShutdown{
-			ID: int(c.assocID)})
+	l.notify(&SctpShutdown{
+		ID: int(c.assocID)})
+
+	// all data from the peer is already received
+	if con, ok := l.conn(c.assocID); ok {
+		con.closeAssoc(&AssocError{Err: ErrShutdown})
 	}
 }
 
 // PartialDelivery is the error type that indicate
 // the association is engaged in a partial delivery of a message.
 type PartialDelivery struct {
-	ID  int
-	Err error
+	EventTime
+	ID     int
+	Stream int
+	Err    error
 }
 
 func (e *PartialDelivery) Error() string {
@@ -464,8 +742,12 @@ func (e *PartialDelivery) Error() string {
 		return "<nil>"
 	}
 	return fmt.Sprintf(
-		"association(id=%d) is engaged in a partial delivery of a message %s",
-		e.ID, e.Err)
+		"association(id=%d) is engaged in a partial delivery of a message on stream %d %s",
+		e.ID, e.Stream, e.Err)
+}
+
+func (e *PartialDelivery) AssocID() int {
+	return e.ID
 }
 
 func (l *SCTPListener) partialDeliveryNotify(buf []byte) {
@@ -475,23 +757,31 @@ func (l *SCTPListener) partialDeliveryNotify(buf []byte) {
 		length     uint32
 		indication uint32
 		assocID    assocT
+		stream     uint32
+		seq        uint32
 	}
 
 	c := (*ntfy)(unsafe.Pointer(&buf[0]))
 	var e error
 	if c.indication == 0 {
 		e = fmt.Errorf("SCTP_PARTIAL_DELIVERY_ABORTED")
+
+		// drop the aborted message, it will never be completed.
+		// flags is 1 if the message is unordered with interleaving.
+		if con, ok := l.conn(c.assocID); ok {
+			con.discard(uint16(c.stream), c.flags&0x1 == 0x1)
+		}
 	}
-	if Notificator != nil {
-		Notificator(&PartialDelivery{
-			ID:  int(c.assocID),
-			Err: e})
-	}
+	l.notify(&PartialDelivery{
+		ID:     int(c.assocID),
+		Stream: int(c.stream),
+		Err:    e})
 }
 
 // AdaptationIndication is the error type that indicate
 // peer sends an Adaptation Layer Indication parameter.
 type AdaptationIndication struct {
+	EventTime
 	ID        int
 	Indicator int
 }
@@ -505,6 +795,10 @@ func (e *AdaptationIndication) Error() string {
 		e.ID, e.Indicator)
 }
 
+func (e *AdaptationIndication) AssocID() int {
+	return e.ID
+}
+
 func (l *SCTPListener) adaptationIndicationNotify(buf []byte) {
 	type ntfy struct {
 		satype   uint16
@@ -515,16 +809,15 @@ func (l *SCTPListener) adaptationIndicationNotify(buf []byte) {
 	}
 
 	c := (*ntfy)(unsafe.Pointer(&buf[0]))
-	if Notificator != nil {
-		Notificator(&AdaptationIndication{
-			ID:        int(c.assocID),
-			Indicator: int(c.adaptInd)})
-	}
+	l.notify(&AdaptationIndication{
+		ID:        int(c.assocID),
+		Indicator: int(c.adaptInd)})
 }
 
 // SenderDry is the error type that indicate
 // the SCTP stack has no more user data to send or retransmit.
 type SenderDry struct {
+	EventTime
 	ID        int
 	Indicator int
 }
@@ -537,6 +830,10 @@ func (e *SenderDry) Error() string {
 		"association(id=%d) has no more data to send or retransmit", e.ID)
 }
 
+func (e *SenderDry) AssocID() int {
+	return e.ID
+}
+
 func (l *SCTPListener) senderDryNotify(buf []byte) {
 	type ntfy struct {
 		drtype  uint16
@@ -546,8 +843,252 @@ func (l *SCTPListener) senderDryNotify(buf []byte) {
 	}
 
 	c := (*ntfy)(unsafe.Pointer(&buf[0]))
-	if Notificator != nil {
-		Notificator(&SenderDry{
+	l.notify(&SenderDry{
+		ID: int(c.assocID)})
+}
+
+func reconfResult(flags, denied, failed uint16) error {
+	switch {
+	case flags&denied == denied:
+		return fmt.Errorf("request denied")
+	case flags&failed == failed:
+		return fmt.Errorf("request failed")
+	}
+	return nil
+}
+
+// SctpStreamReset is the error type that indicate
+// streams of the association are reset.
+type SctpStreamReset struct {
+	EventTime
+	ID       int
+	Stream   []int
+	Incoming bool
+	Outgoing bool
+	Err      error
+}
+
+func (e *SctpStreamReset) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	s := "all"
+	if len(e.Stream) != 0 {
+		s = fmt.Sprint(e.Stream)
+	}
+	if e.Err != nil {
+		return fmt.Sprintf(
+			"reset streams %s of association(id=%d) failed, %s", s, e.ID, e.Err)
+	}
+	return fmt.Sprintf(
+		"streams %s of association(id=%d) are reset", s, e.ID)
+}
+
+func (e *SctpStreamReset) AssocID() int {
+	return e.ID
+}
+
+func (l *SCTPListener) streamResetNotify(buf []byte) {
+	type ntfy struct {
+		strtype uint16
+		flags   uint16
+		length  uint32
+		assocID assocT
+	}
+
+	c := (*ntfy)(unsafe.Pointer(&buf[0]))
+	h := unsafe.Sizeof(*c)
+	n := uintptr(len(buf))
+	if uintptr(c.length) < n {
+		n = uintptr(c.length)
+	}
+
+	s := []int{}
+	for o := h; o+2 <= n; o += 2 {
+		s = append(s, int(*(*uint16)(unsafe.Pointer(&buf[o]))))
+	}
+	l.notify(&SctpStreamReset{
+		ID:       int(c.assocID),
+		Stream:   s,
+		Incoming: c.flags&sctpStreamResetInSsn == sctpStreamResetInSsn,
+		Outgoing: c.flags&sctpStreamResetOutSsn == sctpStreamResetOutSsn,
+		Err: reconfResult(c.flags,
+			sctpStreamResetDenied, sctpStreamResetFailed)})
+}
+
+// SctpAssocReset is the error type that indicate
+// TSN and streams of the association are reset.
+type SctpAssocReset struct {
+	EventTime
+	ID        int
+	LocalTSN  uint32
+	RemoteTSN uint32
+	Err       error
+}
+
+func (e *SctpAssocReset) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	if e.Err != nil {
+		return fmt.Sprintf(
+			"reset association(id=%d) failed, %s", e.ID, e.Err)
+	}
+	return fmt.Sprintf(
+		"association(id=%d) is reset", e.ID)
+}
+
+func (e *SctpAssocReset) AssocID() int {
+	return e.ID
+}
+
+func (l *SCTPListener) assocResetNotify(buf []byte) {
+	type ntfy struct {
+		artype    uint16
+		flags     uint16
+		length    uint32
+		assocID   assocT
+		localTsn  uint32
+		remoteTsn uint32
+	}
+
+	c := (*ntfy)(unsafe.Pointer(&buf[0]))
+	l.notify(&SctpAssocReset{
+		ID:        int(c.assocID),
+		LocalTSN:  c.localTsn,
+		RemoteTSN: c.remoteTsn,
+		Err: reconfResult(c.flags,
+			sctpAssocResetDenied, sctpAssocResetFailed)})
+}
+
+// SctpStreamChange is the error type that indicate
+// streams are added to the association.
+type SctpStreamChange struct {
+	EventTime
+	ID      int
+	OStream int
+	IStream int
+	Err     error
+}
+
+func (e *SctpStreamChange) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	if e.Err != nil {
+		return fmt.Sprintf(
+			"add streams to association(id=%d) failed, %s", e.ID, e.Err)
+	}
+	return fmt.Sprintf(
+		"streams of association(id=%d) are changed to in=%d, out=%d",
+		e.ID, e.IStream, e.OStream)
+}
+
+func (e *SctpStreamChange) AssocID() int {
+	return e.ID
+}
+
+func (l *SCTPListener) streamChangeNotify(buf []byte) {
+	type ntfy struct {
+		sctype   uint16
+		flags    uint16
+		length   uint32
+		assocID  assocT
+		instrms  uint16
+		outstrms uint16
+	}
+
+	c := (*ntfy)(unsafe.Pointer(&buf[0]))
+	l.notify(&SctpStreamChange{
+		ID:      int(c.assocID),
+		OStream: int(c.outstrms),
+		IStream: int(c.instrms),
+		Err: reconfResult(c.flags,
+			sctpStreamChangeDenied, sctpStreamChangeFailed)})
+}
+
+// SctpAuthNewKey is the error type that indicate
+// the key is made active for the association.
+type SctpAuthNewKey struct {
+	EventTime
+	ID  int
+	Key int
+}
+
+func (e *SctpAuthNewKey) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf(
+		"auth key %d is made active on association(id=%d)", e.Key, e.ID)
+}
+
+func (e *SctpAuthNewKey) AssocID() int {
+	return e.ID
+}
+
+// SctpAuthFreeKey is the error type that indicate
+// the deactivated key is no longer used by the association.
+type SctpAuthFreeKey struct {
+	EventTime
+	ID  int
+	Key int
+}
+
+func (e *SctpAuthFreeKey) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf(
+		"auth key %d is no longer used on association(id=%d)", e.Key, e.ID)
+}
+
+func (e *SctpAuthFreeKey) AssocID() int {
+	return e.ID
+}
+
+// SctpAuthNoAuth is the error type that indicate
+// the peer does not support SCTP-AUTH.
+type SctpAuthNoAuth struct {
+	EventTime
+	ID int
+}
+
+func (e *SctpAuthNoAuth) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf(
+		"peer of association(id=%d) does not support authentication", e.ID)
+}
+
+func (e *SctpAuthNoAuth) AssocID() int {
+	return e.ID
+}
+
+func (l *SCTPListener) authNotify(buf []byte) {
+	type ntfy struct {
+		authType     uint16
+		flags        uint16
+		length       uint32
+		keynumber    uint16
+		altkeynumber uint16
+		indication   uint32
+		assocID      assocT
+	}
+
+	c := (*ntfy)(unsafe.Pointer(&buf[0]))
+	switch c.indication {
+	case sctpAuthNewKey:
+		l.notify(&SctpAuthNewKey{
+			ID:  int(c.assocID),
+			Key: int(c.keynumber)})
+	case sctpAuthFreeKey:
+		l.notify(&SctpAuthFreeKey{
+			ID:  int(c.assocID),
+			Key: int(c.keynumber)})
+	case sctpAuthNoAuth:
+		l.notify(&SctpAuthNoAuth{
 			ID: int(c.assocID)})
 	}
 }
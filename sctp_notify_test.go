@@ -0,0 +1,50 @@
+package extnet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseCauses(t *testing.T) {
+	b := []byte{
+		0x00, 0x01, 0x00, 0x08, 0x00, 0x05, 0x00, 0x00, // invalid stream
+		0x00, 0x0c, 0x00, 0x0a, 'r', 'e', 'a', 's', 'o', 'n', 0x00, 0x00, // user abort
+		0x00, 0x06, 0x00, 0x08, 0xc1, 0x00, 0x00, 0x04} // unrecognized chunk
+
+	c := parseCauses(b)
+	if len(c) != 3 {
+		t.Fatalf("number of causes %d is not 3", len(c))
+	}
+	if c[0].Code != CauseInvalidStream || c[0].Stream != 5 {
+		t.Errorf("invalid stream cause is not decoded: %s", c[0])
+	}
+	if c[1].Code != CauseUserInitiatedAbort || c[1].Reason != "reason" {
+		t.Errorf("user abort cause is not decoded: %s", c[1])
+	}
+	if c[2].Code != CauseUnrecognizedChunk || len(c[2].Chunk) != 4 || c[2].Chunk[0] != 0xc1 {
+		t.Errorf("unrecognized chunk cause is not decoded: %s", c[2])
+	}
+
+	var err error = &SctpRemoteError{ID: 1, Err: c[0].Code, Causes: c}
+	var cause *ErrorCause
+	if !errors.As(err, &cause) {
+		t.Errorf("cause is not available by errors.As")
+	} else if cause.Code != CauseInvalidStream {
+		t.Errorf("cause %s is not the first cause", cause)
+	}
+	var causes ErrorCauses
+	if !errors.As(err, &causes) || len(causes) != 3 {
+		t.Errorf("causes are not available by errors.As")
+	}
+
+	// ABORT chunk without error causes
+	if e := abortError(0, []byte{}); e.Err != ErrAborted {
+		t.Errorf("abort without causes %s is not ErrAborted", e)
+	}
+	if e := abortError(0, nil); e.Err != ErrCommLost {
+		t.Errorf("lost association %s is not ErrCommLost", e)
+	}
+	if e := abortError(CauseUserInitiatedAbort, b[8:20]); e.Err != ErrAborted || e.Reason != "reason" {
+		t.Errorf("user abort %s is not decoded", e)
+	}
+}
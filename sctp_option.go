@@ -0,0 +1,481 @@
+package extnet
+
+import (
+	"net"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// sctpSocket is the socket and the association of SCTPConn,
+// or the socket of SCTPListener that the association ID is 0.
+// Options of the listener are the default of new associations.
+type sctpSocket struct {
+	id assocT
+
+	// f is replaced by PeelOff, it must be accessed by file()
+	f  *sockFile
+	fm sync.RWMutex
+}
+
+func (s *sctpSocket) file() *sockFile {
+	s.fm.RLock()
+	defer s.fm.RUnlock()
+	return s.f
+}
+
+func (s *sctpSocket) sock() int {
+	return s.file().sock
+}
+
+func (s *sctpSocket) setFile(f *sockFile) {
+	s.fm.Lock()
+	s.f = f
+	s.fm.Unlock()
+}
+
+// SyscallConn returns a raw network connection of the socket.
+// The socket is nonblocking and handled by the runtime network poller.
+func (s *sctpSocket) SyscallConn() (syscall.RawConn, error) {
+	rc, e := s.file().rawConn()
+	if e != nil {
+		return nil, s.opError("syscallconn", e)
+	}
+	return rc, nil
+}
+
+func (s *sctpSocket) opError(op string, e error) error {
+	r := &net.OpError{
+		Op:  op,
+		Net: "sctp",
+		Err: e}
+	if ptr, n, e := sctpGetladdrs(s.sock(), s.id); e == nil {
+		r.Source = resolveFromRawAddr(ptr, n)
+		sctpFreeladdrs(ptr)
+	}
+	if s.id == 0 {
+		return r
+	}
+	if ptr, n, e := sctpGetpaddrs(s.sock(), s.id); e == nil {
+		r.Addr = resolveFromRawAddr(ptr, n)
+		sctpFreepaddrs(ptr)
+	}
+	return r
+}
+
+func (s *sctpSocket) getOpt(op string, opt int, p unsafe.Pointer, l uintptr) error {
+	if e := getSockOpt(s.sock(), opt, p, &l); e != nil {
+		return s.opError(op, e)
+	}
+	return nil
+}
+
+func (s *sctpSocket) setOpt(op string, opt int, p unsafe.Pointer, l uintptr) error {
+	if e := setSockOpt(s.sock(), opt, p, l); e != nil {
+		return s.opError(op, e)
+	}
+	return nil
+}
+
+func (s *sctpSocket) getAssocValue(op string, opt int) (uint32, error) {
+	v, e := getAssocValue(s.sock(), opt, s.id)
+	if e != nil {
+		return 0, s.opError(op, e)
+	}
+	return v, nil
+}
+
+func (s *sctpSocket) setAssocValue(op string, opt int, v uint32) error {
+	if e := setAssocValue(s.sock(), opt, s.id, v); e != nil {
+		return s.opError(op, e)
+	}
+	return nil
+}
+
+func (s *sctpSocket) getInt(op string, opt int) (int, error) {
+	v := int32(0)
+	e := s.getOpt(op, opt, unsafe.Pointer(&v), unsafe.Sizeof(v))
+	return int(v), e
+}
+
+func (s *sctpSocket) setInt(op string, opt int, v int) error {
+	i := int32(v)
+	return s.setOpt(op, opt, unsafe.Pointer(&i), unsafe.Sizeof(i))
+}
+
+// RtoInfo is the retransmission timeout parameters in milliseconds.
+type RtoInfo struct {
+	Initial int
+	Min     int
+	Max     int
+}
+
+type rtoinfo struct {
+	assocID assocT
+	ini     uint32
+	max     uint32
+	min     uint32
+}
+
+// RtoInfo returns retransmit timer options.
+func (s *sctpSocket) RtoInfo() (*RtoInfo, error) {
+	attr := rtoinfo{assocID: s.id}
+	l := unsafe.Sizeof(attr)
+	p := unsafe.Pointer(&attr)
+
+	if e := s.getOpt("getrtoinfo", sctpRtoInfo, p, l); e != nil {
+		return nil, e
+	}
+	return &RtoInfo{
+		Initial: int(attr.ini),
+		Min:     int(attr.min),
+		Max:     int(attr.max)}, nil
+}
+
+// SetRtoInfo set retransmit timer options
+func (s *sctpSocket) SetRtoInfo(ini, min, max int) error {
+	attr := rtoinfo{
+		assocID: s.id,
+		ini:     uint32(ini),
+		max:     uint32(max),
+		min:     uint32(min)}
+	l := unsafe.Sizeof(attr)
+	p := unsafe.Pointer(&attr)
+
+	return s.setOpt("setrtoinfo", sctpRtoInfo, p, l)
+}
+
+// Associnfo is the association parameters.
+// CookieLife is in milliseconds.
+type Associnfo struct {
+	PeerRwnd    int
+	LocalRwnd   int
+	CookieLife  int
+	AssocMaxRxt int
+	NumPeerDest int
+}
+
+// Associnfo returns association parameter.
+func (s *sctpSocket) Associnfo() (*Associnfo, error) {
+	attr := assocparams{assocID: s.id}
+	l := unsafe.Sizeof(attr)
+	p := unsafe.Pointer(&attr)
+
+	if e := s.getOpt("getassocinfo", sctpAssocInfo, p, l); e != nil {
+		return nil, e
+	}
+	return &Associnfo{
+		PeerRwnd:    int(attr.pRwnd),
+		LocalRwnd:   int(attr.lRwnd),
+		CookieLife:  int(attr.cLife),
+		AssocMaxRxt: int(attr.assocMaxRxt),
+		NumPeerDest: int(attr.numPeerDest)}, nil
+}
+
+// SetAssocinfo set association parameter
+func (s *sctpSocket) SetAssocinfo(pRwnd, lRwnd, cLife, assocMaxRxt, numPeerDest int) error {
+	attr := assocparams{
+		assocID:     s.id,
+		pRwnd:       uint32(pRwnd),
+		lRwnd:       uint32(lRwnd),
+		cLife:       uint32(cLife),
+		assocMaxRxt: uint16(assocMaxRxt),
+		numPeerDest: uint16(numPeerDest)}
+	l := unsafe.Sizeof(attr)
+	p := unsafe.Pointer(&attr)
+
+	return s.setOpt("setassocinfo", sctpAssocInfo, p, l)
+}
+
+// InitMsg is the parameters of INIT chunk.
+// It is used for new associations.
+type InitMsg struct {
+	OutStream   int
+	InStream    int
+	MaxAttempts int
+	InitTimeout time.Duration
+}
+
+type initmsg struct {
+	o uint16
+	i uint16
+	a uint16
+	t uint16
+}
+
+func (m *InitMsg) raw() initmsg {
+	return initmsg{
+		o: uint16(m.OutStream),
+		i: uint16(m.InStream),
+		a: uint16(m.MaxAttempts),
+		t: uint16(m.InitTimeout / time.Millisecond)}
+}
+
+// InitMsg returns the parameters of INIT chunk.
+func (s *sctpSocket) InitMsg() (*InitMsg, error) {
+	attr := initmsg{}
+	l := unsafe.Sizeof(attr)
+	p := unsafe.Pointer(&attr)
+
+	if e := s.getOpt("getinitmsg", sctpInitMsg, p, l); e != nil {
+		return nil, e
+	}
+	return &InitMsg{
+		OutStream:   int(attr.o),
+		InStream:    int(attr.i),
+		MaxAttempts: int(attr.a),
+		InitTimeout: time.Duration(attr.t) * time.Millisecond}, nil
+}
+
+// SetInitMsg set the parameters of INIT chunk.
+func (s *sctpSocket) SetInitMsg(m InitMsg) error {
+	attr := m.raw()
+	l := unsafe.Sizeof(attr)
+	p := unsafe.Pointer(&attr)
+
+	return s.setOpt("setinitmsg", sctpInitMsg, p, l)
+}
+
+// Nodelay returns true if Nagle-like algorithm is disabled.
+func (s *sctpSocket) Nodelay() (bool, error) {
+	v, e := s.getInt("getnodelay", sctpNodelay)
+	return v != 0, e
+}
+
+// SetNodelay set delay answer or not
+func (s *sctpSocket) SetNodelay(attr bool) error {
+	v := 0
+	if attr {
+		v = 1
+	}
+	return s.setInt("setnodelay", sctpNodelay, v)
+}
+
+// MaxSeg returns maximum fragment size of data chunk.
+func (s *sctpSocket) MaxSeg() (int, error) {
+	v, e := s.getAssocValue("getmaxseg", sctpMaxSeg)
+	return int(v), e
+}
+
+// SetMaxSeg set maximum fragment size of data chunk,
+// 0 means the size is decided by the path MTU.
+func (s *sctpSocket) SetMaxSeg(v int) error {
+	return s.setAssocValue("setmaxseg", sctpMaxSeg, uint32(v))
+}
+
+// MaxBurst returns maximum number of packets sent at once.
+func (s *sctpSocket) MaxBurst() (int, error) {
+	v, e := s.getAssocValue("getmaxburst", sctpMaxBurst)
+	return int(v), e
+}
+
+// SetMaxBurst set maximum number of packets sent at once,
+// 0 means no limit.
+func (s *sctpSocket) SetMaxBurst(v int) error {
+	return s.setAssocValue("setmaxburst", sctpMaxBurst, uint32(v))
+}
+
+// SackInfo is the parameters of delayed SACK.
+// SACK is sent after Delay or after Freq packets are received.
+type SackInfo struct {
+	Delay time.Duration
+	Freq  int
+}
+
+type sackinfo struct {
+	assocID assocT
+	delay   uint32
+	freq    uint32
+}
+
+// DelayedSack returns the parameters of delayed SACK.
+func (s *sctpSocket) DelayedSack() (*SackInfo, error) {
+	attr := sackinfo{assocID: s.id}
+	l := unsafe.Sizeof(attr)
+	p := unsafe.Pointer(&attr)
+
+	if e := s.getOpt("getdelayedsack", sctpDelayedSack, p, l); e != nil {
+		return nil, e
+	}
+	return &SackInfo{
+		Delay: time.Duration(attr.delay) * time.Millisecond,
+		Freq:  int(attr.freq)}, nil
+}
+
+// SetDelayedSack set the parameters of delayed SACK,
+// SACK is not delayed if Freq is 1.
+func (s *sctpSocket) SetDelayedSack(i SackInfo) error {
+	attr := sackinfo{
+		assocID: s.id,
+		delay:   uint32(i.Delay / time.Millisecond),
+		freq:    uint32(i.Freq)}
+	l := unsafe.Sizeof(attr)
+	p := unsafe.Pointer(&attr)
+
+	return s.setOpt("setdelayedsack", sctpDelayedSack, p, l)
+}
+
+// Context returns the default context of send messages.
+func (s *sctpSocket) Context() (uint32, error) {
+	return s.getAssocValue("getcontext", sctpContext)
+}
+
+// SetContext set the default context of send messages.
+func (s *sctpSocket) SetContext(v uint32) error {
+	return s.setAssocValue("setcontext", sctpContext, v)
+}
+
+type sndinfo struct {
+	sid     uint16
+	flags   uint16
+	ppid    uint32
+	context uint32
+	assocID assocT
+}
+
+// DefaultSendInfo returns the default attribute of send messages.
+// Only Stream, PPID, Context, Unordered and SackImmediately are available.
+func (s *sctpSocket) DefaultSendInfo() (*SendInfo, error) {
+	attr := sndinfo{assocID: s.id}
+	l := unsafe.Sizeof(attr)
+	p := unsafe.Pointer(&attr)
+
+	if e := s.getOpt("getsndinfo", sctpDefaultSndInfo, p, l); e != nil {
+		return nil, e
+	}
+	return &SendInfo{
+		Stream:          attr.sid,
+		PPID:            attr.ppid,
+		Context:         attr.context,
+		Unordered:       attr.flags&sctpUnordered == sctpUnordered,
+		SackImmediately: attr.flags&sctpSackImmediately == sctpSackImmediately}, nil
+}
+
+// SetDefaultSendInfo set the default attribute of send messages.
+// Only Stream, PPID, Context, Unordered and SackImmediately are used.
+func (s *sctpSocket) SetDefaultSendInfo(i SendInfo) error {
+	attr := sndinfo{
+		sid:     i.Stream,
+		ppid:    i.PPID,
+		context: i.Context,
+		assocID: s.id}
+	if i.Unordered {
+		attr.flags |= sctpUnordered
+	}
+	if i.SackImmediately {
+		attr.flags |= sctpSackImmediately
+	}
+	l := unsafe.Sizeof(attr)
+	p := unsafe.Pointer(&attr)
+
+	return s.setOpt("setsndinfo", sctpDefaultSndInfo, p, l)
+}
+
+// DefaultPRInfo returns the default partial reliability policy.
+func (s *sctpSocket) DefaultPRInfo() (*PRInfo, error) {
+	attr := defaultPrinfo{assocID: s.id}
+	l := unsafe.Sizeof(attr)
+	p := unsafe.Pointer(&attr)
+
+	if e := s.getOpt("getprinfo", sctpDefaultPrinfo, p, l); e != nil {
+		return nil, e
+	}
+	return &PRInfo{
+		Policy: PRPolicy(attr.policy),
+		Value:  attr.value}, nil
+}
+
+// SetDefaultPRInfo set the default partial reliability policy,
+// it is used for messages sent without PR in SendInfo.
+func (s *sctpSocket) SetDefaultPRInfo(i PRInfo) error {
+	attr := defaultPrinfo{
+		assocID: s.id,
+		policy:  uint16(i.Policy),
+		value:   i.Value}
+	l := unsafe.Sizeof(attr)
+	p := unsafe.Pointer(&attr)
+
+	return s.setOpt("setprinfo", sctpDefaultPrinfo, p, l)
+}
+
+// PartialDeliveryPoint returns the message size that
+// partial delivery is started.
+func (s *sctpSocket) PartialDeliveryPoint() (int, error) {
+	v := uint32(0)
+	e := s.getOpt("getpdpoint", sctpPartialDeliveryPoint,
+		unsafe.Pointer(&v), unsafe.Sizeof(v))
+	return int(v), e
+}
+
+// SetPartialDeliveryPoint set the message size that
+// partial delivery is started.
+func (s *sctpSocket) SetPartialDeliveryPoint(v int) error {
+	i := uint32(v)
+	return s.setOpt("setpdpoint", sctpPartialDeliveryPoint,
+		unsafe.Pointer(&i), unsafe.Sizeof(i))
+}
+
+// DisableFragments returns true if fragmentation of messages is disabled.
+func (s *sctpSocket) DisableFragments() (bool, error) {
+	v, e := s.getInt("getdisablefragments", sctpDisableFragments)
+	return v != 0, e
+}
+
+// SetDisableFragments set fragmentation of messages disabled or not,
+// the message larger than the path MTU is failed to send if disabled.
+func (s *sctpSocket) SetDisableFragments(attr bool) error {
+	v := 0
+	if attr {
+		v = 1
+	}
+	return s.setInt("setdisablefragments", sctpDisableFragments, v)
+}
+
+// Autoclose returns idle time that associations are closed automatically,
+// 0 means disabled.
+func (s *sctpSocket) Autoclose() (time.Duration, error) {
+	v, e := s.getInt("getautoclose", sctpAutoclose)
+	return time.Duration(v) * time.Second, e
+}
+
+// SetAutoclose set idle time that associations are closed automatically
+// in seconds, 0 means disabled.
+// It is available only for one-to-many style socket.
+func (s *sctpSocket) SetAutoclose(t time.Duration) error {
+	return s.setInt("setautoclose", sctpAutoclose, int(t/time.Second))
+}
+
+// ReadBuffer returns the size of the socket receive buffer.
+func (s *sctpSocket) ReadBuffer() (int, error) {
+	v, e := getSockBuf(s.sock(), sockRcvBuf)
+	if e != nil {
+		return 0, s.opError("getreadbuffer", e)
+	}
+	return v, nil
+}
+
+// SetReadBuffer set the size of the socket receive buffer.
+func (s *sctpSocket) SetReadBuffer(v int) error {
+	if e := setSockBuf(s.sock(), sockRcvBuf, v); e != nil {
+		return s.opError("setreadbuffer", e)
+	}
+	return nil
+}
+
+// WriteBuffer returns the size of the socket send buffer.
+func (s *sctpSocket) WriteBuffer() (int, error) {
+	v, e := getSockBuf(s.sock(), sockSndBuf)
+	if e != nil {
+		return 0, s.opError("getwritebuffer", e)
+	}
+	return v, nil
+}
+
+// SetWriteBuffer set the size of the socket send buffer.
+func (s *sctpSocket) SetWriteBuffer(v int) error {
+	if e := setSockBuf(s.sock(), sockSndBuf, v); e != nil {
+		return s.opError("setwritebuffer", e)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package extnet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestListenerOption(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	d0 := &SCTPDialer{
+		LocalAddr:   a0,
+		OutStream:   8,
+		InStream:    6,
+		InitTimeout: time.Second}
+	l0a, e := d0.Listen()
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+	l0 := l0a.(*SCTPListener)
+
+	if m, e := l0.InitMsg(); e != nil {
+		t.Errorf("get initmsg failed: %s", e)
+	} else if m.OutStream != 8 || m.InStream != 6 || m.InitTimeout != time.Second {
+		t.Errorf("initmsg out=%d, in=%d, timeout=%s is not equal out=8, in=6, timeout=1s",
+			m.OutStream, m.InStream, m.InitTimeout)
+	}
+
+	if e = l0.SetAutoclose(time.Second * 30); e != nil {
+		t.Errorf("set autoclose failed: %s", e)
+	} else if v, e := l0.Autoclose(); e != nil {
+		t.Errorf("get autoclose failed: %s", e)
+	} else if v != time.Second*30 {
+		t.Errorf("autoclose %s is not equal 30s", v)
+	}
+
+	if e = l0.SetRtoInfo(500, 100, 3000); e != nil {
+		t.Errorf("set rtoinfo failed: %s", e)
+	} else if r, e := l0.RtoInfo(); e != nil {
+		t.Errorf("get rtoinfo failed: %s", e)
+	} else if r.Initial != 500 || r.Min != 100 || r.Max != 3000 {
+		t.Errorf("rtoinfo %d/%d/%d is not equal 500/100/3000", r.Initial, r.Min, r.Max)
+	}
+
+	if v, e := l0.ReadBuffer(); e != nil {
+		t.Errorf("get read buffer failed: %s", e)
+	} else if v == 0 {
+		t.Errorf("read buffer must not be 0")
+	}
+
+	e = l0.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
+
+func TestConnOption(t *testing.T) {
+	Notificator = func(e error) { t.Log(e) }
+
+	a0, e := ResolveSCTPAddr("sctp", testAddrs[0])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+	a1, e := ResolveSCTPAddr("sctp", testAddrs[1])
+	if e != nil {
+		t.Fatalf("address generation failure: %s", e)
+	}
+
+	l0, e := ListenSCTP("sctp", a0)
+	if e != nil {
+		t.Fatalf("listen faied: %s", e)
+	}
+
+	c1, e := DialSCTP(a1, a0)
+	if e != nil {
+		t.Fatalf("dial faied: %s", e)
+	}
+
+	_, e = l0.Accept()
+	if e != nil {
+		t.Errorf("accept faied: %s", e)
+	}
+
+	if a, e := c1.Associnfo(); e != nil {
+		t.Errorf("get associnfo failed: %s", e)
+	} else if a.NumPeerDest != len(a0.IP) {
+		t.Errorf("number of peer destinations %d is not equal %d",
+			a.NumPeerDest, len(a0.IP))
+	}
+
+	if e = c1.SetNodelay(true); e != nil {
+		t.Errorf("set nodelay failed: %s", e)
+	} else if v, e := c1.Nodelay(); e != nil {
+		t.Errorf("get nodelay failed: %s", e)
+	} else if !v {
+		t.Errorf("nodelay is not enabled")
+	}
+
+	if _, e = c1.MaxSeg(); e != nil {
+		t.Errorf("get maxseg failed: %s", e)
+	}
+
+	if e = c1.SetMaxBurst(8); e != nil {
+		t.Errorf("set max burst failed: %s", e)
+	} else if v, e := c1.MaxBurst(); e != nil {
+		t.Errorf("get max burst failed: %s", e)
+	} else if v != 8 {
+		t.Errorf("max burst %d is not equal 8", v)
+	}
+
+	if e = c1.SetDelayedSack(SackInfo{Delay: time.Millisecond * 100, Freq: 2}); e != nil {
+		t.Errorf("set delayed sack failed: %s", e)
+	} else if v, e := c1.DelayedSack(); e != nil {
+		t.Errorf("get delayed sack failed: %s", e)
+	} else if v.Delay != time.Millisecond*100 || v.Freq != 2 {
+		t.Errorf("delayed sack %s/%d is not equal 100ms/2", v.Delay, v.Freq)
+	}
+
+	if e = c1.SetContext(10); e != nil {
+		t.Errorf("set context failed: %s", e)
+	} else if v, e := c1.Context(); e != nil {
+		t.Errorf("get context failed: %s", e)
+	} else if v != 10 {
+		t.Errorf("context %d is not equal 10", v)
+	}
+
+	if e = c1.SetDefaultSendInfo(SendInfo{Stream: 1, PPID: 46}); e != nil {
+		t.Errorf("set default sndinfo failed: %s", e)
+	} else if v, e := c1.DefaultSendInfo(); e != nil {
+		t.Errorf("get default sndinfo failed: %s", e)
+	} else if v.Stream != 1 || v.PPID != 46 {
+		t.Errorf("default sndinfo %d/%d is not equal 1/46", v.Stream, v.PPID)
+	}
+
+	if e = c1.SetPartialDeliveryPoint(4096); e != nil {
+		t.Errorf("set partial delivery point failed: %s", e)
+	} else if v, e := c1.PartialDeliveryPoint(); e != nil {
+		t.Errorf("get partial delivery point failed: %s", e)
+	} else if v != 4096 {
+		t.Errorf("partial delivery point %d is not equal 4096", v)
+	}
+
+	if e = c1.SetDisableFragments(true); e != nil {
+		t.Errorf("set disable fragments failed: %s", e)
+	} else if v, e := c1.DisableFragments(); e != nil {
+		t.Errorf("get disable fragments failed: %s", e)
+	} else if !v {
+		t.Errorf("fragments is not disabled")
+	}
+
+	if e = c1.SetWriteBuffer(65536); e != nil {
+		t.Errorf("set write buffer failed: %s", e)
+	} else if v, e := c1.WriteBuffer(); e != nil {
+		t.Errorf("get write buffer failed: %s", e)
+	} else if v < 65536 {
+		t.Errorf("write buffer %d is smaller than 65536", v)
+	}
+
+	e = c1.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+
+	e = l0.Close()
+	if e != nil {
+		t.Errorf("close faied: %s", e)
+	}
+}
@@ -2,31 +2,128 @@ package extnet
 
 import (
 	"log"
+	"sync"
 	"syscall"
+	"time"
 	"unsafe"
 )
 
 const (
 	ipprotoSctp      = 0x84
 	sctpBindxAddAddr = 0x00008001
-	// sctpBindxRemAddr = 0x00008002
+	sctpBindxRemAddr = 0x00008002
 
 	sctpEoF       = 0x0100
 	sctpAbort     = 0x0200
 	sctpUnordered = 0x0400
-	// SCTP_ADDR_OVER = 0x0800
+	sctpAddrOver  = 0x0800
 	// SCTP_SENDALL = 0x1000
 	// SCTP_EOR = 0x2000
-	// SCTP_SACK_IMMEDIATELY = 0x4000
+	sctpSackImmediately = 0x4000
 
 	// solSctp     = 132
 	sctpRtoInfo   = 0x00000001
 	sctpAssocInfo = 0x00000002
 	sctpInitMsg   = 0x00000003
 	sctpNodelay   = 0x00000004
-	sctpEvents    = 0x0000000c
+
+	sctpMaxSeg               = 0x0000000e
+	sctpMaxBurst             = 0x00000019
+	sctpDelayedSack          = 0x0000000f
+	sctpContext              = 0x0000001a
+	sctpDefaultSndInfo       = 0x00000021
+	sctpPartialDeliveryPoint = 0x00000011
+	sctpDisableFragments     = 0x00000009
+	sctpAutoclose            = 0x00000005
+
+	sockRcvBuf = syscall.SO_RCVBUF
+	sockSndBuf = syscall.SO_SNDBUF
+
+	sctpGetPeerAddrInfo    = 0x00000101
+	sctpPrimaryAddr        = 0x00000007
+	sctpSetPeerPrimaryAddr = 0x00000006
+
+	sctpActive      = 0x0001
+	sctpInactive    = 0x0002
+	sctpUnconfirmed = 0x0200
+	sctpPf          = 0x0800
+	sctpEvents      = 0x0000000c
+
+	sctpStatus = 0x00000100
+
+	sctpClosed           = 0x0000
+	sctpCookieWait       = 0x0002
+	sctpCookieEchoed     = 0x0004
+	sctpEstablished      = 0x0008
+	sctpShutdownPending  = 0x0080
+	sctpShutdownSent     = 0x0010
+	sctpShutdownReceived = 0x0020
+	sctpShutdownAckSent  = 0x0040
+
+	sctpEvent              = 0x0000001e
+	sctpReconfigSupported  = 0x00000029
+	sctpEnableStreamReset  = 0x00000900
+	sctpResetStreams       = 0x00000901
+	sctpResetAssoc         = 0x00000902
+	sctpAddStreams         = 0x00000903
+	sctpEnableResetStream  = 0x0001
+	sctpEnableResetAssoc   = 0x0002
+	sctpEnableChangeAssoc  = 0x0004
+	sctpStreamResetIn      = 0x0001
+	sctpStreamResetOut     = 0x0002
+	sctpStreamResetInSsn   = 0x0001
+	sctpStreamResetOutSsn  = 0x0002
+	sctpStreamResetDenied  = 0x0004
+	sctpStreamResetFailed  = 0x0008
+	sctpAssocResetDenied   = 0x0004
+	sctpAssocResetFailed   = 0x0008
+	sctpStreamChangeDenied = 0x0004
+	sctpStreamChangeFailed = 0x0008
+
+	sctpPrSupported    = 0x00000026
+	sctpDefaultPrinfo  = 0x00000022
+	sctpPrAssocStatus  = 0x00000108
+	sctpPrStreamStatus = 0x00000107
+	sctpPrSctpNone     = 0x0000
+	sctpPrSctpTTL      = 0x0001
+	sctpPrSctpRtx      = 0x0003
+	sctpPrSctpPrio     = 0x0002
+	sctpPrSctpMask     = 0x000f
+	sctpPrSctpAll      = 0x000f
+
+	sctpStreamScheduler      = 0x00001203
+	sctpStreamSchedulerValue = 0x00001204
+	sctpSsFcfs               = 0x00000005
+	sctpSsPrio               = 0x00000003
+	sctpSsRr                 = 0x00000001
+	sctpSsWfq                = 0x00000004 // fair bandwidth
+
+	sctpAuthSupported     = 0x00000027
+	sctpAuthChunk         = 0x00000012
+	sctpHmacIdent         = 0x00000014
+	sctpAuthKey           = 0x00000013
+	sctpAuthActiveKey     = 0x00000015
+	sctpAuthDeleteKey     = 0x00000016
+	sctpAuthDeactivateKey = 0x0000001d
+	sctpPeerAuthChunks    = 0x00000102
+	sctpLocalAuthChunks   = 0x00000103
+
+	sctpPeerAddrParams  = 0x0000000a
+	sppHbEnable         = 0x00000001
+	sppHbDisable        = 0x00000002
+	sppHbDemand         = 0x00000004
+	sppPmtudEnable      = 0x00000008
+	sppPmtudDisable     = 0x00000010
+	sppSackdelayEnable  = 0x00000000 // not supported
+	sppSackdelayDisable = 0x00000000 // not supported
+	sppHbTimeIsZero     = 0x00000080
+
+	sctpAuthNewKey  = 0x0001
+	sctpAuthFreeKey = 0x0003
+	sctpAuthNoAuth  = 0x0002
 
 	msgNotification          = 0x1000
+	msgEoR                   = 0x0008
 	sctpAssocChange          = 0x0001
 	sctpPeerAddrChange       = 0x0002
 	sctpRemoteError          = 0x0003
@@ -35,6 +132,10 @@ const (
 	sctpAdaptationIndication = 0x0006
 	sctpPartialDeliveryEvent = 0x0007
 	sctpSenderDryEvent       = 0x000a
+	sctpAuthenticationEvent  = 0x0008
+	sctpStreamResetEvent     = 0x0009
+	sctpAssocResetEvent      = 0x000c
+	sctpStreamChangeEvent    = 0x000d
 
 	sctpCommUp       = 0x0001
 	sctpCommLost     = 0x0002
@@ -52,6 +153,51 @@ const (
 
 type assocT uint32
 
+type defaultPrinfo struct {
+	policy  uint16
+	value   uint32
+	assocID assocT
+}
+
+type assocparams struct {
+	assocID     assocT
+	pRwnd       uint32
+	lRwnd       uint32
+	cLife       uint32
+	assocMaxRxt uint16
+	numPeerDest uint16
+}
+
+// struct sctp_paddrinfo has the address before the association ID.
+type paddrinfo struct {
+	addr    sockaddrStorage
+	assocID assocT
+	state   int32
+	cwnd    uint32
+	srtt    uint32
+	rto     uint32
+	mtu     uint32
+}
+
+// primAddr is struct sctp_setprim and struct sctp_setpeerprim.
+type primAddr struct {
+	addr    sockaddrStorage
+	assocID assocT
+	_       [4]byte
+}
+
+type status struct {
+	assocID   assocT
+	state     int32
+	rwnd      uint32
+	unackdata uint16
+	penddata  uint16
+	instrms   uint16
+	outstrms  uint16
+	fragPoint uint32
+	primary   paddrinfo
+}
+
 var (
 	fsctpBindx      *syscall.Proc
 	fsctpConnectx   *syscall.Proc
@@ -116,7 +262,34 @@ func init() {
 	}
 }
 
-func setNotify(fd int) error {
+// struct sctp_paddrparams has no SACK delay.
+func (p *paddrparams) marshal() []byte {
+	b := make([]byte, 152)
+	copy(b[0:128], p.addr[:])
+	putUint32(b[128:], uint32(p.assocID))
+	putUint32(b[132:], p.hbinterval)
+	putUint32(b[136:], p.pathmtu)
+	putUint32(b[140:], p.flags)
+	putUint16(b[148:], p.pathmaxrxt)
+	return b
+}
+
+func (p *paddrparams) unmarshal(b []byte) {
+	copy(p.addr[:], b[0:128])
+	p.assocID = assocT(getUint32(b[128:]))
+	p.hbinterval = getUint32(b[132:])
+	p.pathmtu = getUint32(b[136:])
+	p.flags = getUint32(b[140:])
+	p.pathmaxrxt = getUint16(b[148:])
+}
+
+// defaultNotifications is subscribed if SCTPDialer does not specify.
+const defaultNotifications = NotifyAssocChange | NotifyShutdown |
+	NotifyStreamReset | NotifyAssocReset | NotifyStreamChange
+
+// setEvents subscribes data io event and notifications n
+// by the legacy SCTP_EVENTS.
+func setEvents(fd int, n Notification) error {
 	type opt struct {
 		dataIo          uint8
 		association     uint8
@@ -130,19 +303,25 @@ func setNotify(fd int) error {
 		senderDry       uint8
 		streamReset     uint8
 	}
+	on := func(f Notification) uint8 {
+		if n&f != 0 {
+			return 1
+		}
+		return 0
+	}
 
 	event := opt{
 		dataIo:          1,
-		association:     1,
-		address:         0,
-		sendFailure:     0,
-		peerError:       0,
-		shutdown:        1,
-		partialDelivery: 0,
-		adaptationLayer: 0,
-		authentication:  0,
-		senderDry:       0,
-		streamReset:     0}
+		association:     on(NotifyAssocChange),
+		address:         on(NotifyPeerAddrChange),
+		sendFailure:     on(NotifySendFailed),
+		peerError:       on(NotifyRemoteError),
+		shutdown:        on(NotifyShutdown),
+		partialDelivery: on(NotifyPartialDelivery),
+		adaptationLayer: on(NotifyAdaptation),
+		authentication:  on(NotifyAuthentication),
+		senderDry:       on(NotifySenderDry),
+		streamReset:     on(NotifyStreamReset)}
 	l := unsafe.Sizeof(event)
 	p := unsafe.Pointer(&event)
 
@@ -158,18 +337,53 @@ func setSockOpt(fd, opt int, p unsafe.Pointer, l uintptr) error {
 		int32(l))
 }
 
-func sockOpenV4() (int, error) {
+func getSockOpt(fd, opt int, p unsafe.Pointer, l *uintptr) error {
+	sl := int32(*l)
+	e := syscall.Getsockopt(
+		syscall.Handle(fd),
+		ipprotoSctp,
+		int32(opt),
+		(*byte)(p),
+		&sl)
+	if e != nil {
+		return e
+	}
+	*l = uintptr(sl)
+	return nil
+}
+
+func enableInterleaving(fd int) error {
+	return syscall.EWINDOWS
+}
+
+func getSockBuf(fd, opt int) (int, error) {
+	v := int32(0)
+	l := int32(unsafe.Sizeof(v))
+	e := syscall.Getsockopt(
+		syscall.Handle(fd),
+		syscall.SOL_SOCKET,
+		int32(opt),
+		(*byte)(unsafe.Pointer(&v)),
+		&l)
+	return int(v), e
+}
+
+func setSockBuf(fd, opt, v int) error {
+	return syscall.SetsockoptInt(syscall.Handle(fd), syscall.SOL_SOCKET, opt, v)
+}
+
+func sockOpenV4(st int) (int, error) {
 	sock, e := syscall.Socket(
 		syscall.AF_INET,
-		syscall.SOCK_SEQPACKET,
+		st,
 		ipprotoSctp)
 	return int(sock), e
 }
 
-func sockOpenV6() (int, error) {
+func sockOpenV6(st int) (int, error) {
 	sock, e := syscall.Socket(
 		syscall.AF_INET6,
-		syscall.SOCK_SEQPACKET,
+		st,
 		ipprotoSctp)
 	return int(sock), e
 }
@@ -178,6 +392,11 @@ func sockListen(fd int) error {
 	return syscall.Listen(syscall.Handle(fd), BacklogSize)
 }
 
+func sockAccept(fd int) (int, error) {
+	nfd, _, e := syscall.Accept(syscall.Handle(fd))
+	return int(nfd), e
+}
+
 func sockClose(fd int) error {
 	e1 := syscall.Shutdown(syscall.Handle(fd), syscall.SHUT_RD)
 	e2 := syscall.Closesocket(syscall.Handle(fd))
@@ -187,12 +406,55 @@ func sockClose(fd int) error {
 	return e2
 }
 
-func sctpBindx(fd int, ptr unsafe.Pointer, l int) error {
+// sockShutdown starts shutdown of the association on one-to-one style socket.
+func sockShutdown(fd int) error {
+	return syscall.Shutdown(syscall.Handle(fd), syscall.SHUT_WR)
+}
+
+// sockAbort makes close of one-to-one style socket abort the association.
+func sockAbort(fd int) error {
+	return syscall.SetsockoptLinger(syscall.Handle(fd), syscall.SOL_SOCKET, syscall.SO_LINGER,
+		&syscall.Linger{Onoff: 1, Linger: 0})
+}
+
+// sockFile is the blocking socket, the runtime network poller
+// is not available for SCTP socket on Windows.
+type sockFile struct {
+	sock int
+	once sync.Once
+}
+
+func newSockFile(fd int) (*sockFile, error) {
+	return &sockFile{sock: fd}, nil
+}
+
+func (s *sockFile) read(fn func(fd int) error) error {
+	return fn(s.sock)
+}
+
+func (s *sockFile) write(t time.Time, fn func(fd int) error) error {
+	return fn(s.sock)
+}
+
+// close closes the socket, blocking calls on the socket are canceled.
+func (s *sockFile) close() (e error) {
+	e = syscall.EINVAL
+	s.once.Do(func() {
+		e = sockClose(s.sock)
+	})
+	return
+}
+
+func (s *sockFile) rawConn() (syscall.RawConn, error) {
+	return nil, syscall.EWINDOWS
+}
+
+func sctpBindx(fd int, ptr unsafe.Pointer, l, flag int) error {
 	n, _, e := fsctpBindx.Call(
 		uintptr(fd),
 		uintptr(ptr),
 		uintptr(l),
-		sctpBindxAddAddr)
+		uintptr(flag))
 	if int(n) < 0 {
 		return e
 	}
@@ -212,6 +474,10 @@ func sctpConnectx(fd int, ptr unsafe.Pointer, l int) (assocT, error) {
 	return t, nil
 }
 
+func sctpPeeloff(fd int, id assocT) (int, error) {
+	return -1, syscall.EWINDOWS
+}
+
 func sctpSend(fd int, b []byte, info *sndrcvInfo, flag int) (int, error) {
 	buf := uintptr(0)
 	if len(b) != 0 {
@@ -229,27 +495,26 @@ func sctpSend(fd int, b []byte, info *sndrcvInfo, flag int) (int, error) {
 	return int(n), nil
 }
 
-func sctpPolling(fd int) error {
-	addr := &syscall.RawSockaddrInet4{
-		Family: syscall.AF_INET,
-		Port:   0,
-		Addr:   [4]byte{0x7f, 0x00, 0x00, 0x01}}
+func sctpSendmsg(fd int, b []byte, ptr unsafe.Pointer, l uintptr, info *sndrcvInfo) (int, error) {
+	buf := uintptr(0)
+	if len(b) != 0 {
+		buf = uintptr(unsafe.Pointer(&b[0]))
+	}
 	n, _, e := fsctpSendMsg.Call(
 		uintptr(fd),
-		uintptr(unsafe.Pointer(&[]byte{0x00}[0])),
-		uintptr(1),
-		uintptr(unsafe.Pointer(addr)),
-		uintptr(unsafe.Sizeof(*addr)),
-		uintptr(CloseNotifyPpid),   // ppid
-		uintptr(0),                 // flags
-		uintptr(0),                 // stream_no
-		uintptr(0),                 // timetolive
-		uintptr(CloseNotifyCotext)) // context
-
+		buf,
+		uintptr(len(b)),
+		uintptr(ptr),
+		l,
+		uintptr(info.ppid),
+		uintptr(info.flags),
+		uintptr(info.stream),
+		uintptr(info.timetolive),
+		uintptr(info.context))
 	if int(n) < 0 {
-		return e
+		return -1, e
 	}
-	return nil
+	return int(n), nil
 }
 
 func sctpRecvmsg(fd int, b []byte, info *sndrcvInfo, flag *int) (int, error) {
@@ -298,3 +563,24 @@ func sctpGetpaddrs(fd int, id assocT) (unsafe.Pointer, int, error) {
 func sctpFreepaddrs(addr unsafe.Pointer) {
 	fsctpFreepaddrs.Call(uintptr(addr))
 }
+
+// causeCode returns cause code in notification that is in host byte order.
+func causeCode(v uint16) uint16 {
+	return v
+}
+
+// abortCauses returns error causes in sac_info of association change,
+// sac_info is ABORT chunk with the chunk header.
+// nil is returned if no ABORT chunk is received.
+func abortCauses(info []byte) []byte {
+	if len(info) < 4 {
+		return nil
+	}
+	return info[4:]
+}
+
+// remoteCauses returns error causes in sre_data of remote error,
+// sre_data is ERROR chunk with the chunk header.
+func remoteCauses(code uint16, data []byte) ErrorCauses {
+	return parseCauses(abortCauses(data))
+}